@@ -0,0 +1,94 @@
+package main
+
+import (
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	youTubeIDPattern     = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+	vimeoIDPattern       = regexp.MustCompile(`^[0-9]+$`)
+	dailymotionIDPattern = regexp.MustCompile(`^[A-Za-z0-9]+$`)
+	peerTubeIDPattern    = regexp.MustCompile(`^(?:[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}|[1-9A-HJ-NP-Za-km-z]{22})$`)
+)
+
+// embedURL returns the URL of an embeddable player for a link to a video on
+// one of the supported providers, or an empty string when the link is not a
+// video we know how to embed. PeerTube is federated, so its videos are only
+// embedded from the instances in peerTubeHosts, which the operator chooses.
+func embedURL(link string, peerTubeHosts []string) string {
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	segments := strings.Split(strings.Trim(u.EscapedPath(), "/"), "/")
+
+	switch host {
+	case "youtube.com", "m.youtube.com", "youtube-nocookie.com":
+		var id string
+		switch {
+		case segments[0] == "watch":
+			id = u.Query().Get("v")
+		case len(segments) == 2 && (segments[0] == "shorts" || segments[0] == "embed" || segments[0] == "live"):
+			id = segments[1]
+		}
+		return youTubeEmbed(id)
+	case "youtu.be":
+		return youTubeEmbed(segments[0])
+	case "vimeo.com":
+		id := segments[len(segments)-1]
+		if vimeoIDPattern.MatchString(id) {
+			return "https://player.vimeo.com/video/" + id
+		}
+	case "player.vimeo.com":
+		if len(segments) == 2 && segments[0] == "video" && vimeoIDPattern.MatchString(segments[1]) {
+			return "https://player.vimeo.com/video/" + segments[1]
+		}
+	case "dailymotion.com":
+		if len(segments) == 2 && segments[0] == "video" && dailymotionIDPattern.MatchString(segments[1]) {
+			return "https://www.dailymotion.com/embed/video/" + segments[1]
+		}
+	case "dai.ly":
+		if dailymotionIDPattern.MatchString(segments[0]) {
+			return "https://www.dailymotion.com/embed/video/" + segments[0]
+		}
+	default:
+		if !slices.Contains(peerTubeHosts, strings.ToLower(u.Host)) {
+			return ""
+		}
+		var id string
+		switch {
+		case len(segments) == 2 && segments[0] == "w":
+			id = segments[1]
+		case len(segments) == 3 && segments[0] == "videos" && segments[1] == "watch":
+			id = segments[2]
+		}
+		if id != "" && peerTubeIDPattern.MatchString(id) {
+			return "https://" + u.Host + "/videos/embed/" + id
+		}
+	}
+	return ""
+}
+
+func youTubeEmbed(id string) string {
+	if !youTubeIDPattern.MatchString(id) {
+		return ""
+	}
+	return "https://www.youtube-nocookie.com/embed/" + id
+}
+
+// parseHostList splits a comma-separated list of hosts, such as the value of
+// the -peertube-instances flag, into lower-case hosts.
+func parseHostList(list string) []string {
+	var hosts []string
+	for _, host := range strings.Split(list, ",") {
+		host = strings.ToLower(strings.TrimSpace(host))
+		if host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
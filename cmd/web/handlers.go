@@ -5,7 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"html/template"
+	"io"
+	"math"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -38,10 +42,20 @@ func (app *application) getFeeds(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	app.render(w, http.StatusOK, "feeds.html", feeds)
+	lastRun, nextRun := app.scheduler.Status()
+	app.render(w, http.StatusOK, "feeds.html", map[string]any{
+		"feeds":          feeds,
+		"lastRun":        lastRun,
+		"nextRun":        nextRun,
+		"requestOptions": syndication.RequestOptions{},
+	})
 }
 
-func (app *application) createFeed(w http.ResponseWriter, r *http.Request) {
+// previewFeed fetches the feed at the posted URL and shows it for the user to
+// confirm. Nothing is stored until subscribeFeed is called with the returned
+// preview token. The request options posted along are used for every
+// request and kept with the preview.
+func (app *application) previewFeed(w http.ResponseWriter, r *http.Request) {
 	err := r.ParseForm()
 	if err != nil {
 		app.clientError(w, http.StatusBadRequest)
@@ -49,26 +63,232 @@ func (app *application) createFeed(w http.ResponseWriter, r *http.Request) {
 	}
 
 	url := r.PostForm.Get("feedUrl")
-	feedDetails, err := syndication.ExtractFeedDetails(url)
+	options, err := parseRequestOptions(r.PostForm, syndication.RequestOptions{})
+	if err != nil {
+		app.clientError(w, http.StatusUnprocessableEntity)
+		return
+	}
+	fetcher, err := app.fetcher.WithOptions(options, url)
+	if err != nil {
+		app.clientError(w, http.StatusUnprocessableEntity)
+		return
+	}
+	exists, err := app.queries.FeedURLExists(context.Background(), data.FeedURLExistsParams{
+		FeedUrl: url,
+		Url:     url,
+	})
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	if exists != 0 {
+		app.clientError(w, http.StatusConflict)
+		return
+	}
+
+	candidates, err := fetcher.DiscoverFeeds(r.Context(), url)
+	if err == nil && len(candidates) > 1 {
+		// Let the user pick when a site offers several feeds.
+		app.renderPartial(w, http.StatusOK, "feed-candidates", candidates)
+		return
+	}
+
+	var feedDetails *syndication.Feed
+	if err == nil {
+		feedDetails, err = fetcher.ExtractFeedDetails(r.Context(), candidates[0].URL)
+	}
 	if err != nil {
 		switch {
 		case errors.Is(err, syndication.ErrFeedNotFound):
 			app.notFound(w)
+		case errors.Is(err, syndication.ErrAddressNotAllowed):
+			app.addressNotAllowed(w, err)
 		default:
 			app.serverError(w, err)
 		}
 		return
 	}
 
+	exists, err = app.queries.FeedURLExists(context.Background(), data.FeedURLExistsParams{
+		FeedUrl: feedDetails.FeedURL,
+		Url:     feedDetails.FeedURL,
+	})
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	if exists != 0 {
+		app.clientError(w, http.StatusConflict)
+		return
+	}
+
+	app.renderFeedPreview(w, feedPreview{feed: feedDetails, options: options, fetchedAt: time.Now().UTC()})
+}
+
+// previewScraper scrapes a page with the submitted rules and shows the items
+// they extract, so that the rules can be adjusted before subscribing.
+func (app *application) previewScraper(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	url := strings.TrimSpace(r.PostForm.Get("pageUrl"))
+	rules := syndication.ScraperRules{
+		Item:    strings.TrimSpace(r.PostForm.Get("item")),
+		Title:   strings.TrimSpace(r.PostForm.Get("title")),
+		Link:    strings.TrimSpace(r.PostForm.Get("link")),
+		Date:    strings.TrimSpace(r.PostForm.Get("date")),
+		Content: strings.TrimSpace(r.PostForm.Get("content")),
+	}
+	if url == "" || rules.Validate() != nil {
+		app.clientError(w, http.StatusUnprocessableEntity)
+		return
+	}
+	options, err := parseRequestOptions(r.PostForm, syndication.RequestOptions{})
+	if err != nil {
+		app.clientError(w, http.StatusUnprocessableEntity)
+		return
+	}
+	fetcher, err := app.fetcher.WithOptions(options, url)
+	if err != nil {
+		app.clientError(w, http.StatusUnprocessableEntity)
+		return
+	}
+
+	exists, err := app.queries.FeedURLExists(context.Background(), data.FeedURLExistsParams{
+		FeedUrl: url,
+		Url:     url,
+	})
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	if exists != 0 {
+		app.clientError(w, http.StatusConflict)
+		return
+	}
+
+	feedDetails, err := fetcher.Scrape(r.Context(), url, rules, syndication.CacheValidators{})
+	if err != nil {
+		switch {
+		case errors.Is(err, syndication.ErrAddressNotAllowed):
+			app.addressNotAllowed(w, err)
+		default:
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.renderFeedPreview(w, feedPreview{
+		feed:      feedDetails,
+		rules:     rules,
+		options:   options,
+		fetchedAt: time.Now().UTC(),
+	})
+}
+
+// renderFeedPreview stores the preview until the user subscribes and shows
+// the feed along with its latest entries.
+func (app *application) renderFeedPreview(w http.ResponseWriter, preview feedPreview) {
+	token, err := app.previews.add(preview)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	feedDetails := preview.feed
+	entries := slices.Clone(feedDetails.Entries)
+	slices.SortStableFunc(entries, func(a, b syndication.FeedEntry) int {
+		return strings.Compare(b.Published, a.Published)
+	})
+	entries = entries[:min(len(entries), previewEntryCount)]
+
+	p := bluemonday.UGCPolicy()
+	previewEntries := make([]map[string]any, 0, len(entries))
+	for _, entry := range entries {
+		summary := entry.Description
+		if summary == "" {
+			summary = entry.Content
+		}
+		previewEntries = append(previewEntries, map[string]any{
+			"Title":     entry.Title,
+			"Link":      entry.Link,
+			"Published": entry.Published,
+			"Summary":   template.HTML(p.Sanitize(summary)),
+		})
+	}
+
+	app.renderPartial(w, http.StatusOK, "feed-preview", map[string]any{
+		"token":      token,
+		"feed":       feedDetails,
+		"rules":      preview.rules,
+		"options":    preview.options,
+		"entryCount": len(feedDetails.Entries),
+		"entries":    previewEntries,
+	})
+}
+
+// subscribeFeed stores the previewed feed under the title the user chose
+// along with its entries.
+func (app *application) subscribeFeed(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	preview, ok := app.previews.take(r.PostForm.Get("token"))
+	if !ok {
+		// The preview expired or was already used.
+		app.clientError(w, http.StatusGone)
+		return
+	}
+	feedDetails := preview.feed
+
+	exists, err := app.queries.FeedURLExists(context.Background(), data.FeedURLExistsParams{
+		FeedUrl: feedDetails.FeedURL,
+		Url:     feedDetails.FeedURL,
+	})
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	if exists != 0 {
+		app.clientError(w, http.StatusConflict)
+		return
+	}
+
+	title := strings.TrimSpace(r.PostForm.Get("title"))
+	if title == "" {
+		title = feedDetails.Title
+	}
+
+	checkedAt := preview.fetchedAt
 	now := time.Now().UTC().Format(time.RFC3339)
 
+	var recentEntries int64
+	since := checkedAt.Add(-postingWindow).Format(time.RFC3339)
+	for _, entry := range feedDetails.Entries {
+		if entry.Published == "" || entry.Published >= since {
+			recentEntries++
+		}
+	}
+
 	feed, err := app.queries.CreateFeed(context.Background(), data.CreateFeedParams{
-		Title:     feedDetails.Title,
-		Type:      feedDetails.Type,
-		FeedUrl:   feedDetails.FeedURL,
-		SiteUrl:   feedDetails.SiteURL,
-		UpdatedAt: now,
-		CheckedAt: now,
+		Title:          title,
+		Type:           feedDetails.Type,
+		FeedUrl:        feedDetails.FeedURL,
+		SiteUrl:        feedDetails.SiteURL,
+		UpdatedAt:      now,
+		CheckedAt:      checkedAt.Format(time.RFC3339),
+		Etag:           feedDetails.ETag,
+		LastModified:   feedDetails.LastModified,
+		NextCheckAt:    app.nextCheckAt(checkedAt, feedDetails.UpdateHints, recentEntries).Format(time.RFC3339),
+		ScraperRules:   preview.rules,
+		RequestOptions: preview.options,
+		UpdateHints:    feedDetails.UpdateHints,
 	})
 	if err != nil {
 		switch {
@@ -80,13 +300,22 @@ func (app *application) createFeed(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	entries := buildCreateEntryParams(feed.ID, now, feedDetails.Entries)
-	err = app.queries.CreateMultipleEntry(context.Background(), entries)
+	err = app.storeEntries(context.Background(), feed.ID, now, feedDetails.Entries)
 	if err != nil {
 		app.serverError(w, err)
 		return
 	}
 
+	err = app.refreshFeedIcon(r.Context(), feed, feedDetails.IconURL, checkedAt)
+	if err != nil {
+		app.logger.Warn("Fetching feed icon failed", "feed_title", feed.Title, "error", err)
+	}
+
+	err = app.ensureWebSub(r.Context(), feed, feedDetails, checkedAt)
+	if err != nil {
+		app.logger.Warn("Subscribing to WebSub hub failed", "feed_title", feed.Title, "error", err)
+	}
+
 	w.Header().Add("HX-Redirect", fmt.Sprintf("/feeds/%d/", feed.ID))
 	w.WriteHeader(http.StatusCreated)
 }
@@ -119,9 +348,43 @@ func (app *application) getFeed(w http.ResponseWriter, r *http.Request) {
 	app.render(w, http.StatusOK, "feed.html", map[string]any{
 		"feed":    feed,
 		"entries": entries,
+		"options": feed.RequestOptions,
 	})
 }
 
+// getFeedIcon serves the stored icon of a feed. Icons change rarely, so
+// browsers may reuse them for a day and revalidate them with their hash.
+func (app *application) getFeedIcon(w http.ResponseWriter, r *http.Request) {
+	feedID, err := parseID(r)
+	if err != nil {
+		app.notFound(w)
+		return
+	}
+
+	icon, err := app.queries.GetFeedIcon(context.Background(), feedID)
+	if err != nil && err.Error() != "sql: no rows in result set" {
+		app.serverError(w, err)
+		return
+	}
+	if len(icon.Content) == 0 {
+		// Feeds without an icon are looked up again after a while.
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		app.notFound(w)
+		return
+	}
+
+	etag := fmt.Sprintf("%q", icon.Hash)
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Content-Length", strconv.Itoa(len(icon.Content)))
+	w.Write(icon.Content)
+}
+
 func (app *application) deleteFeed(w http.ResponseWriter, r *http.Request) {
 	feedID, err := parseID(r)
 	if err != nil {
@@ -129,6 +392,42 @@ func (app *application) deleteFeed(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = app.queries.DeleteFeedAliases(context.Background(), feedID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	err = app.queries.DeleteFeedEntryTags(context.Background(), feedID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	err = app.queries.DeleteFeedEnclosures(context.Background(), feedID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	err = app.queries.DeleteFeedEntries(context.Background(), feedID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	err = app.queries.DeleteFeedIcon(context.Background(), feedID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	err = app.unsubscribeWebSub(r.Context(), feedID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
 	err = app.queries.DeleteFeed(context.Background(), feedID)
 	if err != nil {
 		app.serverError(w, err)
@@ -156,13 +455,70 @@ func (app *application) markFeedRead(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
-func (app *application) refreshFeed(w http.ResponseWriter, r *http.Request) {
+func (app *application) enableFeed(w http.ResponseWriter, r *http.Request) {
+	feedID, err := parseID(r)
+	if err != nil {
+		app.notFound(w)
+		return
+	}
+
+	err = app.queries.EnableFeed(context.Background(), feedID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	w.Header().Add("HX-Redirect", "/feeds/")
+	w.WriteHeader(http.StatusOK)
+}
+
+// setFeedFullContent turns extraction of the linked articles of a feed's new
+// entries on or off.
+func (app *application) setFeedFullContent(w http.ResponseWriter, r *http.Request) {
+	feedID, err := parseID(r)
+	if err != nil {
+		app.notFound(w)
+		return
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	var enabled int64
+	if r.PostForm.Get("enabled") == "1" {
+		enabled = 1
+	}
+	err = app.queries.SetFeedFetchFullContent(context.Background(), data.SetFeedFetchFullContentParams{
+		ID:               feedID,
+		FetchFullContent: enabled,
+	})
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	w.Header().Add("HX-Redirect", fmt.Sprintf("/feeds/%d/", feedID))
+	w.WriteHeader(http.StatusOK)
+}
+
+// setFeedRequestOptions replaces the request options of a feed. Saved
+// secrets are kept unless new values are posted or they are cleared.
+func (app *application) setFeedRequestOptions(w http.ResponseWriter, r *http.Request) {
 	feedID, err := parseID(r)
 	if err != nil {
 		app.notFound(w)
 		return
 	}
 
+	err = r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
 	feed, err := app.queries.GetFeed(context.Background(), feedID)
 	if err != nil {
 		switch {
@@ -170,51 +526,154 @@ func (app *application) refreshFeed(w http.ResponseWriter, r *http.Request) {
 			app.notFound(w)
 		default:
 			app.serverError(w, err)
-
 		}
 		return
 	}
 
-	newEntries, err := syndication.GetNewEntries(feed.FeedUrl, feed.Type, feed.CheckedAt)
+	options, err := parseRequestOptions(r.PostForm, feed.RequestOptions)
+	if err != nil {
+		app.clientError(w, http.StatusUnprocessableEntity)
+		return
+	}
+	err = app.queries.SetFeedRequestOptions(context.Background(), data.SetFeedRequestOptionsParams{
+		ID:             feedID,
+		RequestOptions: options,
+	})
 	if err != nil {
 		app.serverError(w, err)
 		return
 	}
 
-	if len(newEntries) > 0 {
-		now := time.Now().UTC().Format(time.RFC3339)
-		entries := buildCreateEntryParams(feed.ID, now, newEntries)
-		err = app.queries.CreateMultipleEntry(context.Background(), entries)
-		if err != nil {
+	w.Header().Add("HX-Redirect", fmt.Sprintf("/feeds/%d/", feedID))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (app *application) refreshFeed(w http.ResponseWriter, r *http.Request) {
+	feedID, err := parseID(r)
+	if err != nil {
+		app.notFound(w)
+		return
+	}
+
+	feed, err := app.queries.GetFeed(context.Background(), feedID)
+	if err != nil {
+		switch {
+		case err.Error() == "sql: no rows in result set":
+			app.notFound(w)
+		default:
 			app.serverError(w, err)
-			return
+
 		}
+		return
+	}
 
-		err = app.queries.UpdateFeedCheckedAt(context.Background(), data.UpdateFeedCheckedAtParams{
-			ID:        feed.ID,
-			CheckedAt: now,
-		})
-		if err != nil {
+	fetched, fetchErr := fetchFeed(r.Context(), app.fetcher, feed)
+	if err := fetchErr; err != nil && !errors.Is(err, syndication.ErrNotModified) {
+		recordErr := app.recordFetchError(context.Background(), feed, err)
+		if recordErr != nil {
+			app.logger.Error("Recording feed error failed", "feed_title", feed.Title, "error", recordErr)
+		}
+		switch {
+		case errors.Is(err, syndication.ErrAddressNotAllowed):
+			app.addressNotAllowed(w, err)
+		default:
 			app.serverError(w, err)
-			return
 		}
+		return
+	}
 
+	err = app.storeFetchedFeed(context.Background(), feed, fetched, fetchErr)
+	if err != nil {
+		app.serverError(w, err)
+		return
 	}
+
 	w.Header().Add("HX-Redirect", fmt.Sprintf("/feeds/%d/", feedID))
 	w.WriteHeader(http.StatusOK)
 }
 
 func (app *application) refreshAllFeeds(w http.ResponseWriter, r *http.Request) {
-	err := app.refreshFeeds()
+	app.scheduler.Trigger()
+	w.Header().Add("HX-Redirect", "/feeds/")
+	w.WriteHeader(http.StatusOK)
+}
+
+// entryView is one of the ways an entry can be read.
+type entryView struct {
+	Name  string
+	Label string
+	body  string
+}
+
+func (app *application) getEntry(w http.ResponseWriter, r *http.Request) {
+	entryID, err := parseID(r)
+	if err != nil {
+		app.notFound(w)
+		return
+	}
+
+	entry, err := app.queries.GetEntry(context.Background(), entryID)
+	if err != nil {
+		if strings.Contains(err.Error(), "sql: no rows in result set") {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	enclosures, err := app.queries.GetEntryEnclosures(context.Background(), entryID)
 	if err != nil {
 		app.serverError(w, err)
 		return
 	}
-	w.Header().Add("HX-Redirect", "/")
-	w.WriteHeader(http.StatusOK)
+
+	tags, err := app.queries.GetEntryTags(context.Background(), entryID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	// The entry can be read as its summary, the content supplied by the
+	// feed, or the article extracted from its page, whichever of these
+	// exist. The fullest is shown unless another is asked for.
+	var views []entryView
+	if entry.Summary != "" && entry.Summary != entry.Content {
+		views = append(views, entryView{Name: "summary", Label: "Summary", body: entry.Summary})
+	}
+	if entry.Content != "" {
+		views = append(views, entryView{Name: "content", Label: "Feed content", body: entry.Content})
+	}
+	if entry.FullContent != "" {
+		views = append(views, entryView{Name: "article", Label: "Full article", body: entry.FullContent})
+	}
+
+	var view entryView
+	if len(views) > 0 {
+		view = views[len(views)-1]
+	}
+	for _, v := range views {
+		if v.Name == r.URL.Query().Get("view") {
+			view = v
+		}
+	}
+
+	p := bluemonday.UGCPolicy()
+	htmlContent := template.HTML(p.Sanitize(view.body))
+	app.render(w, http.StatusOK, "entry.html", map[string]any{
+		"entry":      entry,
+		"content":    htmlContent,
+		"enclosures": enclosures,
+		"tags":       tags,
+		"embedURL":   embedURL(entry.ExternalUrl, app.peerTubeHosts),
+		"views":      views,
+		"view":       view.Name,
+	})
 }
 
-func (app *application) getEntry(w http.ResponseWriter, r *http.Request) {
+// extractEntry downloads the page an entry links to and stores its main
+// content as the entry's full article.
+func (app *application) extractEntry(w http.ResponseWriter, r *http.Request) {
 	entryID, err := parseID(r)
 	if err != nil {
 		app.notFound(w)
@@ -230,10 +689,35 @@ func (app *application) getEntry(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
+	if entry.ExternalUrl == "" {
+		app.clientError(w, http.StatusUnprocessableEntity)
+		return
+	}
+	feed, err := app.queries.GetFeed(context.Background(), entry.FeedID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	fetcher, err := app.fetcher.WithOptions(feed.RequestOptions, feed.FeedUrl)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
 
-	p := bluemonday.UGCPolicy()
-	htmlContent := template.HTML(p.Sanitize(entry.Content))
-	app.render(w, http.StatusOK, "entry.html", map[string]any{"entry": entry, "content": htmlContent})
+	err = app.storeFullContent(r.Context(), fetcher, entry.ID, entry.ExternalUrl)
+	if err != nil {
+		app.logger.Warn("Extracting full content failed", "url", entry.ExternalUrl, "error", err)
+		switch {
+		case errors.Is(err, syndication.ErrArticleNotFound):
+			app.clientError(w, http.StatusUnprocessableEntity)
+		default:
+			app.clientError(w, http.StatusBadGateway)
+		}
+		return
+	}
+
+	w.Header().Add("HX-Redirect", fmt.Sprintf("/entries/%d/?view=article", entry.ID))
+	w.WriteHeader(http.StatusOK)
 }
 
 func (app *application) deleteEntry(w http.ResponseWriter, r *http.Request) {
@@ -243,6 +727,18 @@ func (app *application) deleteEntry(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = app.queries.DeleteEntryEnclosures(context.Background(), id)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	err = app.queries.DeleteEntryTags(context.Background(), id)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
 	err = app.queries.DeleteEntry(context.Background(), id)
 	if err != nil {
 		app.serverError(w, err)
@@ -277,3 +773,242 @@ func (app *application) markEntryRead(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusOK)
 }
+
+// updateEnclosurePosition records where playback of an enclosure stopped, in
+// seconds. Positions past the end of the enclosure are stored as its end.
+func (app *application) updateEnclosurePosition(w http.ResponseWriter, r *http.Request) {
+	enclosureID, err := parseID(r)
+	if err != nil {
+		app.notFound(w)
+		return
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	position, err := strconv.ParseFloat(r.PostForm.Get("position"), 64)
+	if err != nil || math.IsNaN(position) || math.IsInf(position, 0) || position < 0 {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	enclosure, err := app.queries.GetEnclosure(context.Background(), enclosureID)
+	if err != nil {
+		if err.Error() == "sql: no rows in result set" {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+	if enclosure.Duration > 0 {
+		position = min(position, float64(enclosure.Duration))
+	} else {
+		position = min(position, math.MaxInt32)
+	}
+
+	err = app.queries.UpdateEnclosurePosition(context.Background(), data.UpdateEnclosurePositionParams{
+		ID:               enclosureID,
+		PlaybackPosition: int64(position),
+	})
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// getTags lists the tags that have entries, with their unread counts.
+func (app *application) getTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := app.queries.GetTags(context.Background())
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	app.render(w, http.StatusOK, "tags.html", tags)
+}
+
+// getTag lists the entries filed under a tag across all feeds.
+func (app *application) getTag(w http.ResponseWriter, r *http.Request) {
+	tagID, err := parseID(r)
+	if err != nil {
+		app.notFound(w)
+		return
+	}
+
+	tag, err := app.queries.GetTag(context.Background(), tagID)
+	if err != nil {
+		switch {
+		case err.Error() == "sql: no rows in result set":
+			app.notFound(w)
+		default:
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	entries, err := app.queries.GetTagEntries(context.Background(), tagID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.render(w, http.StatusOK, "tag.html", map[string]any{
+		"tag":     tag,
+		"entries": entries,
+	})
+}
+
+// verifyWebSub answers the intent verification a hub makes before a
+// subscription change takes effect. The challenge is echoed back only for
+// changes we asked for: subscribing to the topic we track for the feed while
+// a request for it is outstanding, or unsubscribing from a feed we no longer
+// track. Leases longer than websubMaxLease are cut short.
+func (app *application) verifyWebSub(w http.ResponseWriter, r *http.Request) {
+	feedID, err := parseID(r)
+	if err != nil {
+		app.notFound(w)
+		return
+	}
+
+	query := r.URL.Query()
+	sub, err := app.queries.GetWebSubSubscription(context.Background(), feedID)
+	found := err == nil
+	if err != nil && err.Error() != "sql: no rows in result set" {
+		app.serverError(w, err)
+		return
+	}
+
+	switch query.Get("hub.mode") {
+	case "subscribe":
+		now := time.Now().UTC()
+		if !found || query.Get("hub.topic") != sub.TopicUrl || !awaitsVerification(sub, now) {
+			app.notFound(w)
+			return
+		}
+		lease := websubLease
+		seconds, err := strconv.ParseInt(query.Get("hub.lease_seconds"), 10, 64)
+		if err == nil && seconds > 0 {
+			lease = time.Duration(min(seconds, int64(websubMaxLease/time.Second))) * time.Second
+		}
+		err = app.queries.ActivateWebSubSubscription(context.Background(), data.ActivateWebSubSubscriptionParams{
+			FeedID:         feedID,
+			LeaseExpiresAt: now.Add(lease).Format(time.RFC3339),
+		})
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+	case "unsubscribe":
+		if found {
+			app.notFound(w)
+			return
+		}
+	case "denied":
+		if found && query.Get("hub.topic") == sub.TopicUrl {
+			app.logger.Warn("WebSub hub denied subscription",
+				"feed_id", feedID,
+				"hub_url", sub.HubUrl,
+				"reason", query.Get("hub.reason"),
+			)
+			err = app.queries.SetWebSubSubscriptionState(context.Background(), data.SetWebSubSubscriptionStateParams{
+				FeedID: feedID,
+				State:  websubDenied,
+			})
+			if err != nil {
+				app.serverError(w, err)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	default:
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(query.Get("hub.challenge")))
+}
+
+// receiveWebSub stores the content a hub pushes for a feed as if the feed had
+// just been fetched. Content whose signature does not match the secret is
+// acknowledged but ignored, as the hub must not learn whether it was
+// accepted.
+func (app *application) receiveWebSub(w http.ResponseWriter, r *http.Request) {
+	feedID, err := parseID(r)
+	if err != nil {
+		app.notFound(w)
+		return
+	}
+
+	sub, err := app.queries.GetWebSubSubscription(context.Background(), feedID)
+	if err != nil {
+		switch {
+		case err.Error() == "sql: no rows in result set":
+			// Tells the hub to stop pushing to this callback.
+			app.clientError(w, http.StatusGone)
+		default:
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	feed, err := app.queries.GetFeed(context.Background(), feedID)
+	if err != nil {
+		switch {
+		case err.Error() == "sql: no rows in result set":
+			app.clientError(w, http.StatusGone)
+		default:
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	if app.fetcher.MaxBodySize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, app.fetcher.MaxBodySize)
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		app.clientError(w, http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if !syndication.VerifySignature(sub.Secret, r.Header.Get("X-Hub-Signature"), body) {
+		app.logger.Warn("Ignoring WebSub content with an invalid signature",
+			"feed_title", feed.Title,
+			"hub_url", sub.HubUrl,
+		)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	pushed, err := syndication.ParseFeed(body, feed.Type, feed.FeedUrl, r.Header.Get("Content-Type"))
+	if err != nil {
+		app.logger.Warn("Parsing WebSub content failed", "feed_title", feed.Title, "error", err)
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	err = app.storeEntries(context.Background(), feed.ID, now, pushed.Entries)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	if feed.FetchFullContent != 0 {
+		err = app.fetchFullContent(context.Background(), feed, now)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+	}
+
+	app.logger.Info("Received WebSub content", "feed_title", feed.Title, "entries", len(pushed.Entries))
+	w.WriteHeader(http.StatusAccepted)
+}
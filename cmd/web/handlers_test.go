@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oahshtsua/sammler/internal/data"
+	"github.com/oahshtsua/sammler/internal/syndication"
+)
+
+func TestUpdateEnclosurePosition(t *testing.T) {
+	app, _ := newTestApplication(t)
+	feed := createTestFeed(t, app, syndication.RSS, "https://podcast.example/feed")
+	err := app.storeEntries(t.Context(), feed.ID, time.Now().UTC().Format(time.RFC3339), []syndication.FeedEntry{{
+		ID: "episode-1",
+		Enclosures: []syndication.Enclosure{
+			{URL: "https://podcast.example/1.mp3", Type: "audio/mpeg", Duration: 600},
+			{URL: "https://podcast.example/1.ogg", Type: "audio/ogg"},
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entryID, err := app.queries.GetEntryIDByGuid(t.Context(), data.GetEntryIDByGuidParams{FeedID: feed.ID, Guid: "episode-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	enclosures, err := app.queries.GetEntryEnclosures(t.Context(), entryID)
+	if err != nil || len(enclosures) != 2 {
+		t.Fatalf("got %d enclosures, %v", len(enclosures), err)
+	}
+	timed, untimed := enclosures[0].ID, enclosures[1].ID
+
+	tests := []struct {
+		enclosureID  int64
+		position     string
+		wantCode     int
+		wantPosition int64
+	}{
+		{timed, "42.7", http.StatusOK, 42},
+		{timed, "0", http.StatusOK, 0},
+		{timed, "601", http.StatusOK, 600},
+		{timed, "1e300", http.StatusOK, 600},
+		{untimed, "1e300", http.StatusOK, 1<<31 - 1},
+		{timed, "NaN", http.StatusBadRequest, 0},
+		{timed, "Inf", http.StatusBadRequest, 0},
+		{timed, "-Inf", http.StatusBadRequest, 0},
+		{timed, "-1", http.StatusBadRequest, 0},
+		{timed, "", http.StatusBadRequest, 0},
+		{untimed + 1, "1", http.StatusNotFound, 0},
+	}
+	for _, tt := range tests {
+		form := url.Values{"position": {tt.position}}
+		r := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/enclosures/%d/action/position/", tt.enclosureID), strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		app.router().ServeHTTP(rec, r)
+		if rec.Code != tt.wantCode {
+			t.Errorf("position %q: status = %d, want %d", tt.position, rec.Code, tt.wantCode)
+			continue
+		}
+		if tt.wantCode != http.StatusOK {
+			continue
+		}
+		enclosure, err := app.queries.GetEnclosure(t.Context(), tt.enclosureID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if enclosure.PlaybackPosition != tt.wantPosition {
+			t.Errorf("position %q: stored %d, want %d", tt.position, enclosure.PlaybackPosition, tt.wantPosition)
+		}
+	}
+}
+
+func TestStoreEntriesReplacesEnclosures(t *testing.T) {
+	app, db := newTestApplication(t)
+	feed := createTestFeed(t, app, syndication.RSS, "https://podcast.example/feed")
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	for _, url := range []string{"https://podcast.example/1.mp3", "https://podcast.example/1-fixed.mp3"} {
+		err := app.storeEntries(t.Context(), feed.ID, now, []syndication.FeedEntry{{
+			ID:         "episode-1",
+			Enclosures: []syndication.Enclosure{{URL: url, Type: "audio/mpeg"}},
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	entryID, err := app.queries.GetEntryIDByGuid(t.Context(), data.GetEntryIDByGuidParams{FeedID: feed.ID, Guid: "episode-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	enclosures, err := app.queries.GetEntryEnclosures(t.Context(), entryID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(enclosures) != 1 || enclosures[0].Url != "https://podcast.example/1-fixed.mp3" {
+		t.Errorf("got enclosures %+v, want only the re-uploaded one", enclosures)
+	}
+
+	// An entry that no longer has enclosures loses them all.
+	err = app.storeEntries(t.Context(), feed.ID, now, []syndication.FeedEntry{{ID: "episode-1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	enclosures, err = app.queries.GetEntryEnclosures(t.Context(), entryID)
+	if err != nil || len(enclosures) != 0 {
+		t.Errorf("got %d enclosures, %v; want none", len(enclosures), err)
+	}
+
+	// Deleting the feed deletes its entries and their enclosures.
+	err = app.storeEntries(t.Context(), feed.ID, now, []syndication.FeedEntry{{
+		ID:         "episode-1",
+		Enclosures: []syndication.Enclosure{{URL: "https://podcast.example/1.mp3"}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	app.router().ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/feeds/%d/", feed.ID), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	for _, table := range []string{"entries", "enclosures"} {
+		var count int
+		err = db.QueryRow(`SELECT COUNT(*) FROM ` + table).Scan(&count)
+		if err != nil || count != 0 {
+			t.Errorf("got %d %s after deleting the feed, %v; want none", count, table, err)
+		}
+	}
+}
@@ -1,12 +1,19 @@
 package main
 
 import (
+	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"runtime/debug"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/oahshtsua/sammler/internal/data"
 	"github.com/oahshtsua/sammler/internal/syndication"
@@ -27,6 +34,17 @@ func (app *application) notFound(w http.ResponseWriter) {
 	app.clientError(w, http.StatusNotFound)
 }
 
+// addressNotAllowed reports a fetch the address guard refused, naming the
+// address so that the user can ask the operator to allow it.
+func (app *application) addressNotAllowed(w http.ResponseWriter, err error) {
+	msg := "The address is not allowed."
+	var addrErr *syndication.AddressError
+	if errors.As(err, &addrErr) {
+		msg = fmt.Sprintf("Refusing to fetch: %s. Feeds on private, loopback or link-local addresses have to be allowed by the operator.", addrErr)
+	}
+	http.Error(w, msg, http.StatusForbidden)
+}
+
 func parseID(r *http.Request) (int64, error) {
 	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
 	if err != nil || id < 1 {
@@ -35,20 +53,275 @@ func parseID(r *http.Request) (int64, error) {
 	return id, nil
 }
 
+// parseRequestOptions reads the request options of a submitted form. Headers
+// are given one "Name: value" per line. The headers, password, cookie and
+// proxy of current are kept when left blank, as they are never sent back to
+// the browser, unless clearSecrets is set.
+func parseRequestOptions(form url.Values, current syndication.RequestOptions) (syndication.RequestOptions, error) {
+	options := syndication.RequestOptions{
+		Username:           strings.TrimSpace(form.Get("username")),
+		UserAgent:          strings.TrimSpace(form.Get("userAgent")),
+		InsecureSkipVerify: form.Get("insecureSkipVerify") == "1",
+	}
+	if form.Get("clearSecrets") != "1" {
+		options.Headers = current.Headers
+		options.Password = current.Password
+		options.Cookie = current.Cookie
+		options.ProxyURL = current.ProxyURL
+	}
+
+	if headers := strings.TrimSpace(form.Get("headers")); headers != "" {
+		options.Headers = map[string]string{}
+		for _, line := range strings.Split(headers, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			name, value, ok := strings.Cut(line, ":")
+			if !ok {
+				return options, fmt.Errorf("%w: header %q has no value", syndication.ErrInvalidRequestOptions, line)
+			}
+			options.Headers[http.CanonicalHeaderKey(strings.TrimSpace(name))] = strings.TrimSpace(value)
+		}
+	}
+	if password := form.Get("password"); password != "" {
+		options.Password = password
+	}
+	if cookie := strings.TrimSpace(form.Get("cookie")); cookie != "" {
+		options.Cookie = cookie
+	}
+	if proxyURL := strings.TrimSpace(form.Get("proxyUrl")); proxyURL != "" {
+		options.ProxyURL = proxyURL
+	}
+	return options, options.Validate()
+}
+
+// storeEntries upserts the entries of a feed along with their enclosures and
+// tags in a single transaction. Entries without a date are dated when they
+// were first seen.
+func (app *application) storeEntries(ctx context.Context, feedID int64, now string, entries []syndication.FeedEntry) error {
+	tx, err := app.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := app.queries.WithTx(tx)
+
+	// Entries stored before GUIDs were tracked are keyed by their link. They
+	// take the entry's GUID so that the upsert finds them.
+	legacyLinks, err := qtx.GetLegacyEntryLinks(ctx, feedID)
+	if err != nil {
+		return err
+	}
+
+	entries = slices.Clone(entries)
+	for i, entry := range entries {
+		if guid := entry.GUID(); guid != entry.Link && slices.Contains(legacyLinks, entry.Link) {
+			err := qtx.UpdateLegacyEntryGuid(ctx, data.UpdateLegacyEntryGuidParams{
+				Guid:        guid,
+				FeedID:      feedID,
+				ExternalUrl: entry.Link,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if entry.Published != "" {
+			continue
+		}
+		firstSeen, err := qtx.GetEntryPublishedAt(ctx, data.GetEntryPublishedAtParams{
+			FeedID: feedID,
+			Guid:   entry.GUID(),
+		})
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			firstSeen = now
+		case err != nil:
+			return err
+		}
+		entries[i].Published = firstSeen
+	}
+
+	entryIDs, err := qtx.CreateMultipleEntry(ctx, buildCreateEntryParams(feedID, now, entries))
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		entryID := entryIDs[entry.GUID()]
+		err = storeEntryTags(ctx, qtx, entryID, entry.Categories)
+		if err != nil {
+			return err
+		}
+		err = storeEntryEnclosures(ctx, qtx, entryID, entry.Enclosures)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// storeEntryEnclosures upserts the enclosures of an entry and deletes those
+// the feed no longer lists.
+func storeEntryEnclosures(ctx context.Context, queries *data.Queries, entryID int64, enclosures []syndication.Enclosure) error {
+	urls := make([]string, 0, len(enclosures))
+	for _, enclosure := range enclosures {
+		urls = append(urls, enclosure.URL)
+	}
+	var err error
+	if len(urls) == 0 {
+		err = queries.DeleteEntryEnclosures(ctx, entryID)
+	} else {
+		err = queries.DeleteStaleEntryEnclosures(ctx, data.DeleteStaleEntryEnclosuresParams{
+			EntryID: entryID,
+			Urls:    urls,
+		})
+	}
+	if err != nil {
+		return err
+	}
+	for _, enclosure := range enclosures {
+		err = queries.CreateEnclosure(ctx, data.CreateEnclosureParams{
+			EntryID:  entryID,
+			Url:      enclosure.URL,
+			MimeType: enclosure.Type,
+			Title:    enclosure.Title,
+			Length:   enclosure.Length,
+			Duration: enclosure.Duration,
+			Episode:  enclosure.Episode,
+			ImageUrl: enclosure.ImageURL,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// storeEntryTags replaces the tags of an entry with its current categories.
+func storeEntryTags(ctx context.Context, queries *data.Queries, entryID int64, categories []string) error {
+	err := queries.DeleteEntryTags(ctx, entryID)
+	if err != nil {
+		return err
+	}
+	for _, category := range categories {
+		tagID, err := queries.UpsertTag(ctx, category)
+		if err != nil {
+			return err
+		}
+		err = queries.CreateEntryTag(ctx, data.CreateEntryTagParams{
+			EntryID: entryID,
+			TagID:   tagID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refreshFeedIcon fetches the icon of a feed when it has none yet, when the
+// stored one is older than iconMaxAge or when the feed advertises a new one.
+// iconURL is the icon the feed currently advertises, if any. A feed without a
+// usable icon is recorded with empty content so that it is not retried on
+// every refresh.
+func (app *application) refreshFeedIcon(ctx context.Context, feed data.Feed, iconURL string, now time.Time) error {
+	icon, err := app.queries.GetFeedIcon(ctx, feed.ID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return err
+	default:
+		fetchedAt, _ := time.Parse(time.RFC3339, icon.FetchedAt)
+		changed := iconURL != "" && iconURL != icon.Url
+		if !changed && now.Sub(fetchedAt) < iconMaxAge {
+			return nil
+		}
+		if iconURL == "" {
+			iconURL = icon.Url
+		}
+	}
+
+	fetcher, err := app.fetcher.WithOptions(feed.RequestOptions, feed.FeedUrl)
+	if err != nil {
+		return err
+	}
+	content, err := fetcher.FetchIcon(ctx, iconURL, feed.SiteUrl)
+	if err != nil && !errors.Is(err, syndication.ErrIconNotFound) {
+		return err
+	}
+	var hash string
+	if len(content) > 0 {
+		sum := sha256.Sum256(content)
+		hash = hex.EncodeToString(sum[:16])
+	} else {
+		content = []byte{}
+	}
+
+	return app.queries.UpsertFeedIcon(ctx, data.UpsertFeedIconParams{
+		FeedID:    feed.ID,
+		Url:       iconURL,
+		Content:   content,
+		Hash:      hash,
+		FetchedAt: now.Format(time.RFC3339),
+	})
+}
+
+// fetchFullContent extracts the linked articles of a feed's entries that
+// were stored since the given time. Entries whose article cannot be
+// extracted keep only the content supplied by the feed.
+func (app *application) fetchFullContent(ctx context.Context, feed data.Feed, since string) error {
+	fetcher, err := app.fetcher.WithOptions(feed.RequestOptions, feed.FeedUrl)
+	if err != nil {
+		return err
+	}
+	entries, err := app.queries.GetEntriesWithoutFullContent(ctx, data.GetEntriesWithoutFullContentParams{
+		FeedID:    feed.ID,
+		CreatedAt: since,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		err = app.storeFullContent(ctx, fetcher, entry.ID, entry.ExternalUrl)
+		if err != nil {
+			app.logger.Warn("Extracting full content failed", "url", entry.ExternalUrl, "error", err)
+		}
+	}
+	return nil
+}
+
+// storeFullContent extracts the main content of the page at url with the
+// fetcher of the entry's feed and stores it as the full article of the entry.
+func (app *application) storeFullContent(ctx context.Context, fetcher *syndication.Fetcher, entryID int64, url string) error {
+	content, err := fetcher.FetchArticle(ctx, url)
+	if err != nil {
+		return err
+	}
+	return app.queries.UpdateEntryFullContent(ctx, data.UpdateEntryFullContentParams{
+		ID:          entryID,
+		FullContent: content,
+	})
+}
+
 func buildCreateEntryParams(feedID int64, now string, entries []syndication.FeedEntry) []data.CreateEntryParams {
 	params := make([]data.CreateEntryParams, 0, len(entries))
 	for _, entry := range entries {
 		params = append(params, data.CreateEntryParams{
 			FeedID: feedID,
+			Guid:   entry.GUID(),
 			Title:  entry.Title,
 			Author: sql.NullString{
 				String: entry.Author,
 				Valid:  entry.Author != "",
 			},
-			Content:     entry.Content,
-			ExternalUrl: entry.Link,
-			PublishedAt: entry.Published,
-			CreatedAt:   now,
+			Content:      entry.Content,
+			ExternalUrl:  entry.Link,
+			PublishedAt:  entry.Published,
+			CreatedAt:    now,
+			ThumbnailUrl: entry.Media.ThumbnailURL,
+			Summary:      entry.Description,
 		})
 
 	}
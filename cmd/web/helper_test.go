@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oahshtsua/sammler/internal/data"
+	"github.com/oahshtsua/sammler/internal/syndication"
+)
+
+func TestStoreEntries(t *testing.T) {
+	app, db := newTestApplication(t)
+	feed := createTestFeed(t, app, syndication.RSS, "https://blog.example/feed")
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	// An entry stored before GUIDs were tracked, keyed by its link.
+	err := app.storeEntries(t.Context(), feed.ID, now, []syndication.FeedEntry{
+		{Title: "Old", Link: "https://blog.example/1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var legacyID int64
+	err = db.QueryRow(`SELECT id FROM entries WHERE feed_id = ?`, feed.ID).Scan(&legacyID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := []syndication.FeedEntry{
+		{ID: "post-1", Title: "First", Link: "https://blog.example/1", Categories: []string{"go", "sqlite"}},
+		{ID: "post-2", Title: "Second", Link: "https://blog.example/2", Categories: []string{"go"}},
+	}
+	err = app.storeEntries(t.Context(), feed.ID, now, entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var count int
+	err = db.QueryRow(`SELECT COUNT(*) FROM entries WHERE feed_id = ?`, feed.ID).Scan(&count)
+	if err != nil || count != 2 {
+		t.Fatalf("got %d entries, %v; want 2", count, err)
+	}
+	entryID, err := app.queries.GetEntryIDByGuid(t.Context(), data.GetEntryIDByGuidParams{FeedID: feed.ID, Guid: "post-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entryID != legacyID {
+		t.Errorf("post-1 was stored as entry %d, want the legacy entry %d", entryID, legacyID)
+	}
+	legacyLinks, err := app.queries.GetLegacyEntryLinks(t.Context(), feed.ID)
+	if err != nil || len(legacyLinks) != 0 {
+		t.Errorf("legacy links = %q, %v; want none", legacyLinks, err)
+	}
+
+	for guid, want := range map[string]int{"post-1": 2, "post-2": 1} {
+		id, err := app.queries.GetEntryIDByGuid(t.Context(), data.GetEntryIDByGuidParams{FeedID: feed.ID, Guid: guid})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tags, err := app.queries.GetEntryTags(t.Context(), id)
+		if err != nil || len(tags) != want {
+			t.Errorf("%s: got %d tags, %v; want %d", guid, len(tags), err, want)
+		}
+	}
+}
+
+func TestStoreEntriesRollsBack(t *testing.T) {
+	app, db := newTestApplication(t)
+	feed := createTestFeed(t, app, syndication.RSS, "https://blog.example/feed")
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	_, err := db.Exec(`CREATE TRIGGER fail_tags BEFORE INSERT ON entry_tags BEGIN SELECT RAISE(ABORT, 'no tags'); END`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = app.storeEntries(t.Context(), feed.ID, now, []syndication.FeedEntry{
+		{ID: "post-1", Title: "First", Categories: []string{"go"}},
+	})
+	if err == nil {
+		t.Fatal("storeEntries succeeded, want an error")
+	}
+
+	var count int
+	err = db.QueryRow(`SELECT COUNT(*) FROM entries WHERE feed_id = ?`, feed.ID).Scan(&count)
+	if err != nil || count != 0 {
+		t.Errorf("got %d entries, %v; want none after a failed store", count, err)
+	}
+}
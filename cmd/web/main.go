@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
 	"html/template"
@@ -10,17 +11,35 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/oahshtsua/sammler/internal/data"
+	"github.com/oahshtsua/sammler/internal/syndication"
 )
 
 type application struct {
 	logger    *slog.Logger
+	db        *sql.DB
 	queries   *data.Queries
+	fetcher   *syndication.Fetcher
+	scheduler *scheduler
+	previews  *previewStore
 	templates map[string]*template.Template
 	workers   int
+	// baseURL is the public address of the server. WebSub subscriptions
+	// are only made when it is set.
+	baseURL string
+	// peerTubeHosts are the PeerTube instances whose videos are embedded.
+	peerTubeHosts []string
+
+	minFeedInterval time.Duration
+	maxFeedInterval time.Duration
+	maxFeedErrors   int
 }
 
 func openDB(dsn string) (*sql.DB, error) {
@@ -42,6 +61,18 @@ func main() {
 	port := flag.Int("port", 3456, "Network port")
 	dsn := flag.String("dsn", "sammler.db", "Sqlite database file")
 	workers := flag.Int("workers", 10, "Number of workers to start for fetching feeds")
+	userAgent := flag.String("user-agent", syndication.DefaultUserAgent, "User-Agent header sent when fetching feeds")
+	fetchTimeout := flag.Duration("fetch-timeout", 30*time.Second, "Maximum duration of a single feed request")
+	maxBodySize := flag.Int64("max-body-size", 10<<20, "Maximum size in bytes of a fetched feed or page")
+	maxRedirects := flag.Int("max-redirects", syndication.DefaultMaxRedirects, "Maximum number of redirects followed per request")
+	discoveryCacheTTL := flag.Duration("discovery-cache-ttl", syndication.DefaultDiscoveryCacheTTL, "How long feed discovery results are reused")
+	refreshInterval := flag.Duration("refresh-interval", time.Minute, "Interval between checks for feeds that are due")
+	minFeedInterval := flag.Duration("min-feed-interval", 5*time.Minute, "Shortest interval between fetches of a single feed")
+	maxFeedInterval := flag.Duration("max-feed-interval", 24*time.Hour, "Longest interval between fetches of a single feed")
+	maxFeedErrors := flag.Int("max-feed-errors", 10, "Consecutive fetch failures after which a feed is disabled")
+	fetchAllowlist := flag.String("fetch-allowlist", "", "Comma-separated IP addresses, CIDR networks and host names (*.example.com for subdomains) that may be fetched although they are not public")
+	baseURL := flag.String("base-url", "", "Public URL of the server, used as WebSub callback; push subscriptions are disabled when empty")
+	peerTubeInstances := flag.String("peertube-instances", "", "Comma-separated hosts of PeerTube instances whose videos are embedded in entries")
 
 	flag.Parse()
 
@@ -58,11 +89,36 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+
+	guard, err := syndication.NewAddressGuard(strings.Split(*fetchAllowlist, ","))
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+	if os.Getenv("HTTP_PROXY") != "" || os.Getenv("HTTPS_PROXY") != "" ||
+		os.Getenv("http_proxy") != "" || os.Getenv("https_proxy") != "" {
+		logger.Warn("Proxy environment variables are ignored when fetching feeds; set a proxy in the feed's request options instead")
+	}
+	client := &http.Client{Transport: guard.Transport()}
+	fetcher := syndication.NewFetcher(client, *userAgent, *fetchTimeout, *maxBodySize)
+	fetcher.MaxRedirects = *maxRedirects
+	fetcher.DiscoveryCacheTTL = *discoveryCacheTTL
 	app := application{
 		logger:    logger,
+		db:        db,
 		queries:   data.New(db),
+		fetcher:   fetcher,
+		scheduler: newScheduler(*refreshInterval),
+		previews:  newPreviewStore(),
 		templates: tmplCache,
 		workers:   *workers,
+		baseURL:   *baseURL,
+
+		peerTubeHosts: parseHostList(*peerTubeInstances),
+
+		minFeedInterval: *minFeedInterval,
+		maxFeedInterval: *maxFeedInterval,
+		maxFeedErrors:   *maxFeedErrors,
 	}
 
 	srv := &http.Server{
@@ -73,13 +129,32 @@ func main() {
 		WriteTimeout: 30 * time.Second,
 	}
 
-	app.refreshFeeds()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		app.runScheduler(ctx)
+	}()
+
+	go func() {
+		<-ctx.Done()
+		logger.Info("Shutting down server")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			logger.Error(err.Error())
+		}
+	}()
 
 	logger.Info("Starting server", "port", *port)
 	err = srv.ListenAndServe()
-	if err != nil {
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		logger.Error(err.Error())
 		os.Exit(1)
-
 	}
+
+	wg.Wait()
 }
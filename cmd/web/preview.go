@@ -0,0 +1,83 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/oahshtsua/sammler/internal/syndication"
+)
+
+// previewTTL is how long a fetched feed waits for the user to confirm the
+// subscription before it has to be fetched again.
+const previewTTL = 15 * time.Minute
+
+// previewEntryCount is how many entries are shown in a feed preview.
+const previewEntryCount = 5
+
+// maxPreviews is how many previews are kept at once. The oldest are dropped
+// to make room for new ones.
+const maxPreviews = 100
+
+type feedPreview struct {
+	feed *syndication.Feed
+	// rules are set when the feed was scraped from a page.
+	rules syndication.ScraperRules
+	// options are the request options the feed was fetched with.
+	options   syndication.RequestOptions
+	fetchedAt time.Time
+}
+
+// previewStore keeps feeds that have been fetched for a preview, so that
+// confirming the subscription does not download them again.
+type previewStore struct {
+	mu       sync.Mutex
+	previews map[string]feedPreview
+}
+
+func newPreviewStore() *previewStore {
+	return &previewStore{previews: map[string]feedPreview{}}
+}
+
+// add stores a preview and returns the token it can be taken back with.
+func (ps *previewStore) add(preview feedPreview) (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	for key, stored := range ps.previews {
+		if time.Since(stored.fetchedAt) > previewTTL {
+			delete(ps.previews, key)
+		}
+	}
+	for len(ps.previews) >= maxPreviews {
+		var oldest string
+		for key, stored := range ps.previews {
+			if oldest == "" || stored.fetchedAt.Before(ps.previews[oldest].fetchedAt) {
+				oldest = key
+			}
+		}
+		delete(ps.previews, oldest)
+	}
+	ps.previews[token] = preview
+	return token, nil
+}
+
+// take removes and returns the preview stored under token. It reports false
+// when there is none or it has expired.
+func (ps *previewStore) take(token string) (feedPreview, bool) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	preview, ok := ps.previews[token]
+	delete(ps.previews, token)
+	if !ok || time.Since(preview.fetchedAt) > previewTTL {
+		return feedPreview{}, false
+	}
+	return preview, true
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPreviewStoreEvictsOldest(t *testing.T) {
+	ps := newPreviewStore()
+	start := time.Now().Add(-time.Minute)
+
+	var tokens []string
+	for i := range maxPreviews + 2 {
+		token, err := ps.add(feedPreview{fetchedAt: start.Add(time.Duration(i) * time.Millisecond)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens = append(tokens, token)
+	}
+
+	if n := len(ps.previews); n != maxPreviews {
+		t.Errorf("store holds %d previews, want %d", n, maxPreviews)
+	}
+	for i, token := range tokens {
+		_, ok := ps.take(token)
+		if want := i >= 2; ok != want {
+			t.Errorf("preview %d: taken = %v, want %v", i, ok, want)
+		}
+	}
+}
+
+func TestPreviewStoreExpires(t *testing.T) {
+	ps := newPreviewStore()
+	expired, err := ps.add(feedPreview{fetchedAt: time.Now().Add(-previewTTL - time.Second)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fresh, err := ps.add(feedPreview{fetchedAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := ps.take(expired); ok {
+		t.Error("expired preview was taken")
+	}
+	if _, ok := ps.take(fresh); !ok {
+		t.Error("fresh preview was not taken")
+	}
+	if _, ok := ps.take(fresh); ok {
+		t.Error("preview was taken twice")
+	}
+}
@@ -13,17 +13,32 @@ func (app *application) router() *http.ServeMux {
 
 	mux.HandleFunc("GET /", app.home)
 	mux.HandleFunc("GET /feeds/", app.getFeeds)
-	mux.HandleFunc("POST /feeds/", app.createFeed)
+	mux.HandleFunc("POST /feeds/", app.previewFeed)
+	mux.HandleFunc("POST /feeds/action/scrape/", app.previewScraper)
+	mux.HandleFunc("POST /feeds/action/subscribe/", app.subscribeFeed)
 	mux.HandleFunc("GET /feeds/action/refresh-all/", app.refreshAllFeeds)
 	mux.HandleFunc("GET /feeds/{id}/", app.getFeed)
+	mux.HandleFunc("GET /feeds/{id}/icon", app.getFeedIcon)
 	mux.HandleFunc("DELETE /feeds/{id}/", app.deleteFeed)
 	mux.HandleFunc("POST /feeds/{id}/action/mark-read/", app.markFeedRead)
 	mux.HandleFunc("GET /feeds/{id}/action/refresh/", app.refreshFeed)
+	mux.HandleFunc("POST /feeds/{id}/action/enable/", app.enableFeed)
+	mux.HandleFunc("POST /feeds/{id}/action/full-content/", app.setFeedFullContent)
+	mux.HandleFunc("POST /feeds/{id}/action/request-options/", app.setFeedRequestOptions)
 
 	mux.HandleFunc("GET /entries/{id}/", app.getEntry)
 	mux.HandleFunc("DELETE /entries/{id}/", app.deleteEntry)
 	mux.HandleFunc("POST /entries/{id}/action/mark-read/", app.markEntryRead)
+	mux.HandleFunc("POST /entries/{id}/action/extract/", app.extractEntry)
 	mux.HandleFunc("POST /entries/action/mark-all-read/", app.markEntriesRead)
 
+	mux.HandleFunc("GET /tags/", app.getTags)
+	mux.HandleFunc("GET /tags/{id}/", app.getTag)
+
+	mux.HandleFunc("POST /enclosures/{id}/action/position/", app.updateEnclosurePosition)
+
+	mux.HandleFunc("GET /websub/{id}/", app.verifyWebSub)
+	mux.HandleFunc("POST /websub/{id}/", app.receiveWebSub)
+
 	return mux
 }
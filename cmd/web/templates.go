@@ -9,9 +9,15 @@ import (
 	"time"
 )
 
+// partialsKey is the key of the template cache that holds the partials on
+// their own, for renderPartial.
+const partialsKey = "partials"
+
 var functions = template.FuncMap{
-	"formatDate": formatDate,
-	"ytNoCookie": ytNoCookie,
+	"formatDate":     formatDate,
+	"formatTime":     formatTime,
+	"formatDuration": formatDuration,
+	"mediaKind":      mediaKind,
 }
 
 func newTemplateCache() (map[string]*template.Template, error) {
@@ -40,6 +46,12 @@ func newTemplateCache() (map[string]*template.Template, error) {
 		}
 		cache[name] = ts
 	}
+
+	partials, err := template.New(partialsKey).Funcs(functions).ParseGlob("./ui/templates/partials/*.html")
+	if err != nil {
+		return nil, err
+	}
+	cache[partialsKey] = partials
 	return cache, nil
 }
 
@@ -63,7 +75,49 @@ func formatDate(dt string) string {
 	return val.Format("Jan 02, 2006")
 }
 
-func ytNoCookie(ytURL string) string {
-	_, videoID, _ := strings.Cut(ytURL, "watch?v=")
-	return fmt.Sprintf("https://www.youtube-nocookie.com/embed/%s", videoID)
+func formatTime(t time.Time) string {
+	return t.Local().Format("Jan 02, 2006 15:04")
+}
+
+// formatDuration renders a number of seconds as H:MM:SS or M:SS.
+func formatDuration(seconds int64) string {
+	h, m, s := seconds/3600, seconds%3600/60, seconds%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
+
+// mediaKind reports whether a MIME type can be played by an <audio> or
+// <video> element. It returns an empty string for anything else.
+func mediaKind(mimeType string) string {
+	kind, _, _ := strings.Cut(mimeType, "/")
+	switch kind {
+	case "audio", "video":
+		return kind
+	default:
+		return ""
+	}
+}
+
+// renderPartial executes a single named template, such as a partial swapped
+// in by HTMX, without the base layout.
+func (app *application) renderPartial(w http.ResponseWriter, status int, name string, data any) {
+	ts, ok := app.templates[partialsKey]
+	if !ok {
+		err := fmt.Errorf("the template set '%s' does not exist", partialsKey)
+		app.serverError(w, err)
+		return
+	}
+	if ts.Lookup(name) == nil {
+		err := fmt.Errorf("the template '%s' does not exist", name)
+		app.serverError(w, err)
+		return
+	}
+
+	w.WriteHeader(status)
+	err := ts.ExecuteTemplate(w, name, data)
+	if err != nil {
+		app.serverError(w, err)
+	}
 }
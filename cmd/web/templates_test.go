@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/oahshtsua/sammler/internal/syndication"
+)
+
+func TestRenderPartial(t *testing.T) {
+	t.Chdir("../..")
+	cache, err := newTemplateCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	app, _ := newTestApplication(t)
+	app.templates = cache
+
+	rec := httptest.NewRecorder()
+	app.renderPartial(rec, http.StatusOK, "feed-candidates", []syndication.FeedCandidate{
+		{URL: "https://blog.example/feed.xml", Title: "Blog"},
+	})
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "https://blog.example/feed.xml") {
+		t.Errorf("got %d %q, want the rendered candidates", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	app.renderPartial(rec, http.StatusOK, "no-such-partial", nil)
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("missing partial: status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+}
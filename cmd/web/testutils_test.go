@@ -0,0 +1,92 @@
+package main
+
+import (
+	"database/sql"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oahshtsua/sammler/internal/data"
+	"github.com/oahshtsua/sammler/internal/syndication"
+)
+
+var gooseStatement = regexp.MustCompile(`(?s)-- \+goose StatementBegin\n(.*?)-- \+goose StatementEnd`)
+
+// newTestDB returns a database in a temporary directory with the up
+// migrations applied.
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "sammler.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	files, err := filepath.Glob("../../migrations/*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		up, _, _ := strings.Cut(string(b), "-- +goose Down")
+		for _, m := range gooseStatement.FindAllStringSubmatch(up, -1) {
+			_, err = db.Exec(m[1])
+			if err != nil {
+				t.Fatalf("%s: %v", filepath.Base(file), err)
+			}
+		}
+	}
+	return db
+}
+
+// newTestApplication returns an application backed by a fresh database whose
+// fetcher uses the default HTTP client, so that it can reach httptest
+// servers.
+func newTestApplication(t *testing.T) (*application, *sql.DB) {
+	t.Helper()
+
+	db := newTestDB(t)
+	app := &application{
+		logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		db:        db,
+		queries:   data.New(db),
+		fetcher:   syndication.NewFetcher(nil, "sammler-test", 5*time.Second, 1<<20),
+		scheduler: newScheduler(time.Minute),
+		previews:  newPreviewStore(),
+		workers:   1,
+
+		minFeedInterval: 5 * time.Minute,
+		maxFeedInterval: 24 * time.Hour,
+		maxFeedErrors:   10,
+	}
+	return app, db
+}
+
+// createTestFeed stores a feed of the given type and address.
+func createTestFeed(t *testing.T, app *application, ft syndication.FeedType, feedURL string) data.Feed {
+	t.Helper()
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	feed, err := app.queries.CreateFeed(t.Context(), data.CreateFeedParams{
+		Title:       "Test feed",
+		FeedUrl:     feedURL,
+		SiteUrl:     feedURL,
+		Type:        ft,
+		UpdatedAt:   now,
+		CheckedAt:   now,
+		NextCheckAt: now,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return feed
+}
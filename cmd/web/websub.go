@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/oahshtsua/sammler/internal/data"
+	"github.com/oahshtsua/sammler/internal/syndication"
+)
+
+// WebSub subscription states. A subscription is pending from the moment it
+// is requested until the hub verifies it with the callback.
+const (
+	websubPending = "pending"
+	websubActive  = "active"
+	websubDenied  = "denied"
+)
+
+// websubLease is the subscription duration asked of hubs.
+const websubLease = 10 * 24 * time.Hour
+
+// websubMaxLease is the longest lease accepted from hubs, so that a
+// subscription whose hub has gone away is not trusted for long.
+const websubMaxLease = 30 * 24 * time.Hour
+
+// websubRenewBefore is how long before its lease expires a subscription is
+// renewed.
+const websubRenewBefore = 24 * time.Hour
+
+// websubRetryAfter is how long a request that was not verified, or that was
+// denied, is left before it is made again.
+const websubRetryAfter = 6 * time.Hour
+
+// callbackURL is the address hubs deliver the updates of a feed to.
+func (app *application) callbackURL(feedID int64) string {
+	return fmt.Sprintf("%s/websub/%d/", strings.TrimRight(app.baseURL, "/"), feedID)
+}
+
+// ensureWebSub subscribes to the hub a feed is published through, unless an
+// active subscription to it already exists or a recent request is still
+// pending. Nothing happens when no base URL is configured, as hubs would have
+// no way to reach us.
+func (app *application) ensureWebSub(ctx context.Context, feed data.Feed, fetched *syndication.Feed, now time.Time) error {
+	if app.baseURL == "" || fetched == nil || fetched.HubURL == "" {
+		return nil
+	}
+	topicURL := fetched.TopicURL
+	if topicURL == "" {
+		topicURL = fetched.FeedURL
+	}
+
+	sub, err := app.queries.GetWebSubSubscription(ctx, feed.ID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return err
+	case sub.HubUrl == fetched.HubURL && sub.TopicUrl == topicURL:
+		requestedAt, _ := time.Parse(time.RFC3339, sub.RequestedAt)
+		if sub.State == websubActive || now.Sub(requestedAt) < websubRetryAfter {
+			return nil
+		}
+	}
+
+	secret := sub.Secret
+	if secret == "" {
+		secret, err = newWebSubSecret()
+		if err != nil {
+			return err
+		}
+	}
+	return app.requestWebSub(ctx, data.WebsubSubscription{
+		FeedID:   feed.ID,
+		HubUrl:   fetched.HubURL,
+		TopicUrl: topicURL,
+		Secret:   secret,
+		State:    websubPending,
+	}, now)
+}
+
+// requestWebSub sends a subscription request to the hub and records it. The
+// state of sub is kept, so that renewing an active subscription does not
+// suspend it while the hub verifies the renewal. A request the hub refuses is
+// recorded as denied and retried after websubRetryAfter.
+func (app *application) requestWebSub(ctx context.Context, sub data.WebsubSubscription, now time.Time) error {
+	reqErr := app.fetcher.Subscribe(ctx, syndication.HubRequest{
+		HubURL:      sub.HubUrl,
+		TopicURL:    sub.TopicUrl,
+		CallbackURL: app.callbackURL(sub.FeedID),
+		Secret:      sub.Secret,
+		Lease:       websubLease,
+	})
+	state := sub.State
+	if reqErr != nil {
+		state = websubDenied
+	}
+
+	err := app.queries.UpsertWebSubSubscription(ctx, data.UpsertWebSubSubscriptionParams{
+		FeedID:      sub.FeedID,
+		HubUrl:      sub.HubUrl,
+		TopicUrl:    sub.TopicUrl,
+		Secret:      sub.Secret,
+		State:       state,
+		RequestedAt: now.Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+	return reqErr
+}
+
+// renewWebSubSubscriptions renews the active subscriptions whose lease
+// expires within websubRenewBefore.
+func (app *application) renewWebSubSubscriptions(ctx context.Context) error {
+	if app.baseURL == "" {
+		return nil
+	}
+	now := time.Now().UTC()
+	subs, err := app.queries.GetExpiringWebSubSubscriptions(ctx, data.GetExpiringWebSubSubscriptionsParams{
+		LeaseExpiresAt: now.Add(websubRenewBefore).Format(time.RFC3339),
+		RequestedAt:    now.Add(-websubRetryAfter).Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range subs {
+		err = app.requestWebSub(ctx, sub, now)
+		if err != nil {
+			app.logger.Warn("Renewing WebSub subscription failed",
+				"feed_id", sub.FeedID,
+				"hub_url", sub.HubUrl,
+				"error", err,
+			)
+		}
+	}
+	return nil
+}
+
+// unsubscribeWebSub asks the hub to stop pushing updates of a feed and
+// forgets the subscription. The request is best effort: a hub that is not
+// told keeps pushing until the lease runs out, and the callback answers with
+// 410 Gone meanwhile.
+func (app *application) unsubscribeWebSub(ctx context.Context, feedID int64) error {
+	sub, err := app.queries.GetWebSubSubscription(ctx, feedID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil
+	case err != nil:
+		return err
+	}
+
+	err = app.queries.DeleteWebSubSubscription(ctx, feedID)
+	if err != nil {
+		return err
+	}
+	if app.baseURL == "" {
+		return nil
+	}
+	err = app.fetcher.Unsubscribe(ctx, syndication.HubRequest{
+		HubURL:      sub.HubUrl,
+		TopicURL:    sub.TopicUrl,
+		CallbackURL: app.callbackURL(feedID),
+	})
+	if err != nil {
+		app.logger.Warn("Unsubscribing from WebSub hub failed", "hub_url", sub.HubUrl, "error", err)
+	}
+	return nil
+}
+
+// awaitsVerification reports whether a subscription request was sent to the
+// hub recently enough for its verification to be expected. This is the case
+// for new requests and renewals of active subscriptions, but not for denied
+// ones.
+func awaitsVerification(sub data.WebsubSubscription, now time.Time) bool {
+	if sub.State != websubPending && sub.State != websubActive {
+		return false
+	}
+	requestedAt, err := time.Parse(time.RFC3339, sub.RequestedAt)
+	return err == nil && now.Sub(requestedAt) < websubRetryAfter
+}
+
+// hasActiveWebSub reports whether updates of the feed are currently pushed
+// to us.
+func (app *application) hasActiveWebSub(ctx context.Context, feedID int64, now time.Time) (bool, error) {
+	sub, err := app.queries.GetWebSubSubscription(ctx, feedID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+	return sub.State == websubActive && sub.LeaseExpiresAt > now.Format(time.RFC3339), nil
+}
+
+func newWebSubSecret() (string, error) {
+	b := make([]byte, 24)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,418 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oahshtsua/sammler/internal/data"
+	"github.com/oahshtsua/sammler/internal/syndication"
+)
+
+// testHub is a WebSub hub that accepts every request and records it.
+type testHub struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []url.Values
+}
+
+func newTestHub(t *testing.T) *testHub {
+	hub := &testHub{}
+	hub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseForm()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		hub.mu.Lock()
+		hub.requests = append(hub.requests, r.PostForm)
+		hub.mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(hub.Close)
+	return hub
+}
+
+func (hub *testHub) Requests() []url.Values {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	return append([]url.Values(nil), hub.requests...)
+}
+
+func testRSS(items ...string) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0"?><rss version="2.0"><channel><title>Blog</title><link>https://blog.example/</link>`)
+	for _, guid := range items {
+		fmt.Fprintf(&b, `<item><title>Post %s</title><link>https://blog.example/%s</link><guid>%s</guid></item>`, guid, guid, guid)
+	}
+	b.WriteString(`</channel></rss>`)
+	return b.String()
+}
+
+// newWebSubFixture stores a feed that is published through hub, and fetches
+// it once so that a subscription is requested.
+func newWebSubFixture(t *testing.T, hub *testHub) (*application, *sql.DB, data.Feed, string) {
+	t.Helper()
+
+	var feedURL string
+	publisher := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="hub", <%s>; rel="self"`, hub.URL, feedURL))
+		fmt.Fprint(w, testRSS("1"))
+	}))
+	t.Cleanup(publisher.Close)
+	feedURL = publisher.URL + "/feed"
+
+	app, db := newTestApplication(t)
+	app.baseURL = "https://reader.example/"
+	feed := createTestFeed(t, app, syndication.RSS, feedURL)
+
+	fetched, err := fetchFeed(t.Context(), app.fetcher, feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = app.storeFetchedFeed(t.Context(), feed, fetched, err)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return app, db, feed, feedURL
+}
+
+func verifyRequest(feedID int64, mode, topic string, lease string) *http.Request {
+	query := url.Values{
+		"hub.mode":      {mode},
+		"hub.topic":     {topic},
+		"hub.challenge": {"challenge-123"},
+	}
+	if lease != "" {
+		query.Set("hub.lease_seconds", lease)
+	}
+	return httptest.NewRequest(http.MethodGet, fmt.Sprintf("/websub/%d/?%s", feedID, query.Encode()), nil)
+}
+
+func pushRequest(feedID int64, body, signature string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/websub/%d/", feedID), strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/rss+xml")
+	r.Header.Set("X-Hub-Signature", signature)
+	return r
+}
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWebSubSubscribe(t *testing.T) {
+	hub := newTestHub(t)
+	app, _, feed, feedURL := newWebSubFixture(t, hub)
+
+	requests := hub.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("got %d hub requests, want 1", len(requests))
+	}
+	req := requests[0]
+	want := map[string]string{
+		"hub.mode":          "subscribe",
+		"hub.topic":         feedURL,
+		"hub.callback":      fmt.Sprintf("https://reader.example/websub/%d/", feed.ID),
+		"hub.lease_seconds": fmt.Sprint(int64(websubLease / time.Second)),
+	}
+	for key, value := range want {
+		if got := req.Get(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+
+	sub, err := app.queries.GetWebSubSubscription(t.Context(), feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.State != websubPending {
+		t.Errorf("state = %q, want %q", sub.State, websubPending)
+	}
+	if sub.Secret == "" || sub.Secret != req.Get("hub.secret") {
+		t.Errorf("secret = %q, hub was sent %q", sub.Secret, req.Get("hub.secret"))
+	}
+
+	// A pending request is not sent again on the next fetch.
+	fetched, err := fetchFeed(t.Context(), app.fetcher, feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = app.storeFetchedFeed(t.Context(), feed, fetched, err)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(hub.Requests()); n != 1 {
+		t.Errorf("got %d hub requests after refetching, want 1", n)
+	}
+}
+
+func TestWebSubVerify(t *testing.T) {
+	tests := []struct {
+		name      string
+		state     string
+		requested time.Duration
+		mode      string
+		topic     string
+		lease     string
+		wantCode  int
+		wantState string
+		wantLease time.Duration
+	}{
+		{
+			name:      "pending subscription",
+			state:     websubPending,
+			mode:      "subscribe",
+			lease:     "3600",
+			wantCode:  http.StatusOK,
+			wantState: websubActive,
+			wantLease: time.Hour,
+		},
+		{
+			name:      "default lease",
+			state:     websubPending,
+			mode:      "subscribe",
+			wantCode:  http.StatusOK,
+			wantState: websubActive,
+			wantLease: websubLease,
+		},
+		{
+			name:      "lease beyond the maximum",
+			state:     websubPending,
+			mode:      "subscribe",
+			lease:     "9223372036854775807",
+			wantCode:  http.StatusOK,
+			wantState: websubActive,
+			wantLease: websubMaxLease,
+		},
+		{
+			name:      "renewal",
+			state:     websubActive,
+			mode:      "subscribe",
+			lease:     "3600",
+			wantCode:  http.StatusOK,
+			wantState: websubActive,
+			wantLease: time.Hour,
+		},
+		{
+			name:      "other topic",
+			state:     websubPending,
+			mode:      "subscribe",
+			topic:     "https://elsewhere.example/feed",
+			wantCode:  http.StatusNotFound,
+			wantState: websubPending,
+		},
+		{
+			name:      "denied subscription",
+			state:     websubDenied,
+			mode:      "subscribe",
+			wantCode:  http.StatusNotFound,
+			wantState: websubDenied,
+		},
+		{
+			name:      "stale request",
+			state:     websubPending,
+			requested: -websubRetryAfter - time.Minute,
+			mode:      "subscribe",
+			wantCode:  http.StatusNotFound,
+			wantState: websubPending,
+		},
+		{
+			name:      "unsubscribe from tracked feed",
+			state:     websubActive,
+			mode:      "unsubscribe",
+			wantCode:  http.StatusNotFound,
+			wantState: websubActive,
+		},
+		{
+			name:      "hub denial",
+			state:     websubPending,
+			mode:      "denied",
+			wantCode:  http.StatusOK,
+			wantState: websubDenied,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := newTestHub(t)
+			app, db, feed, feedURL := newWebSubFixture(t, hub)
+			_, err := db.Exec(`UPDATE websub_subscriptions SET state = ?, requested_at = ? WHERE feed_id = ?`,
+				tt.state, time.Now().UTC().Add(tt.requested).Format(time.RFC3339), feed.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			topic := tt.topic
+			if topic == "" {
+				topic = feedURL
+			}
+			rec := httptest.NewRecorder()
+			app.router().ServeHTTP(rec, verifyRequest(feed.ID, tt.mode, topic, tt.lease))
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if tt.wantCode == http.StatusOK && tt.mode == "subscribe" && rec.Body.String() != "challenge-123" {
+				t.Errorf("body = %q, want the challenge", rec.Body.String())
+			}
+
+			sub, err := app.queries.GetWebSubSubscription(t.Context(), feed.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sub.State != tt.wantState {
+				t.Errorf("state = %q, want %q", sub.State, tt.wantState)
+			}
+			if tt.wantLease != 0 {
+				expires, err := time.Parse(time.RFC3339, sub.LeaseExpiresAt)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if d := time.Until(expires); d > tt.wantLease || d < tt.wantLease-time.Minute {
+					t.Errorf("lease expires in %v, want %v", d, tt.wantLease)
+				}
+			}
+		})
+	}
+}
+
+func TestWebSubPush(t *testing.T) {
+	hub := newTestHub(t)
+	app, _, feed, feedURL := newWebSubFixture(t, hub)
+	router := app.router()
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, verifyRequest(feed.ID, "subscribe", feedURL, ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("verification status = %d", rec.Code)
+	}
+	sub, err := app.queries.GetWebSubSubscription(t.Context(), feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entryExists := func(guid string) bool {
+		_, err := app.queries.GetEntryIDByGuid(t.Context(), data.GetEntryIDByGuidParams{
+			FeedID: feed.ID,
+			Guid:   guid,
+		})
+		if err != nil && err != sql.ErrNoRows {
+			t.Fatal(err)
+		}
+		return err == nil
+	}
+
+	body := testRSS("2")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, pushRequest(feed.ID, body, sign(sub.Secret, body)))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("push status = %d", rec.Code)
+	}
+	if !entryExists("2") {
+		t.Error("signed push was not stored")
+	}
+
+	body = testRSS("3")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, pushRequest(feed.ID, body, sign("not the secret", body)))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("push status = %d", rec.Code)
+	}
+	if entryExists("3") {
+		t.Error("push with a bad signature was stored")
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, pushRequest(feed.ID+1, body, sign(sub.Secret, body)))
+	if rec.Code != http.StatusGone {
+		t.Errorf("push to unknown feed status = %d, want %d", rec.Code, http.StatusGone)
+	}
+}
+
+func TestWebSubRenew(t *testing.T) {
+	hub := newTestHub(t)
+	app, db, feed, feedURL := newWebSubFixture(t, hub)
+	router := app.router()
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, verifyRequest(feed.ID, "subscribe", feedURL, "3600"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("verification status = %d", rec.Code)
+	}
+
+	// Nothing is renewed while the lease is far from expiring.
+	_, err := db.Exec(`UPDATE websub_subscriptions SET lease_expires_at = ?, requested_at = ? WHERE feed_id = ?`,
+		time.Now().UTC().Add(2*websubRenewBefore).Format(time.RFC3339),
+		time.Now().UTC().Add(-2*websubRetryAfter).Format(time.RFC3339),
+		feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = app.renewWebSubSubscriptions(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(hub.Requests()); n != 1 {
+		t.Fatalf("got %d hub requests, want 1", n)
+	}
+
+	_, err = db.Exec(`UPDATE websub_subscriptions SET lease_expires_at = ? WHERE feed_id = ?`,
+		time.Now().UTC().Add(time.Hour).Format(time.RFC3339), feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = app.renewWebSubSubscriptions(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := hub.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("got %d hub requests, want 2", len(requests))
+	}
+	if mode := requests[1].Get("hub.mode"); mode != "subscribe" {
+		t.Errorf("renewal mode = %q", mode)
+	}
+
+	// The subscription stays active while the renewal is verified, and is
+	// not renewed twice.
+	active, err := app.hasActiveWebSub(t.Context(), feed.ID, time.Now().UTC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !active {
+		t.Error("subscription is not active during renewal")
+	}
+	err = app.renewWebSubSubscriptions(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(hub.Requests()); n != 2 {
+		t.Errorf("got %d hub requests after renewing again, want 2", n)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, verifyRequest(feed.ID, "subscribe", feedURL, ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("renewal verification status = %d", rec.Code)
+	}
+	sub, err := app.queries.GetWebSubSubscription(t.Context(), feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expires, _ := time.Parse(time.RFC3339, sub.LeaseExpiresAt)
+	if time.Until(expires) < websubLease-time.Minute {
+		t.Errorf("lease expires in %v after renewal, want %v", time.Until(expires), websubLease)
+	}
+}
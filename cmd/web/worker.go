@@ -2,7 +2,8 @@ package main
 
 import (
 	"context"
-	"log"
+	"errors"
+	"slices"
 	"sync"
 	"time"
 
@@ -10,19 +11,122 @@ import (
 	"github.com/oahshtsua/sammler/internal/syndication"
 )
 
+// postingWindow is how far back entries are counted when estimating how often
+// a feed publishes.
+const postingWindow = 30 * 24 * time.Hour
+
+// iconMaxAge is how long a feed's icon, or the lack of one, is kept before it
+// is fetched again.
+const iconMaxAge = 7 * 24 * time.Hour
+
 type Result struct {
-	feedID    int64
-	feedTitle string
-	entries   []syndication.FeedEntry
-	err       error
+	feed    data.Feed
+	fetched *syndication.Feed
+	err     error
+}
+
+// scheduler tracks the periodic background refresh. Runs happen on a single
+// goroutine so they never overlap; manual triggers are coalesced into the
+// next run.
+type scheduler struct {
+	interval time.Duration
+	trigger  chan struct{}
+
+	mu      sync.Mutex
+	lastRun time.Time
+	nextRun time.Time
+}
+
+func newScheduler(interval time.Duration) *scheduler {
+	return &scheduler{
+		interval: interval,
+		trigger:  make(chan struct{}, 1),
+	}
+}
+
+// Trigger requests a refresh as soon as the current run, if any, finishes.
+func (s *scheduler) Trigger() {
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Status returns the time the last run finished and the time the next one is
+// due. Either is zero when unknown.
+func (s *scheduler) Status() (lastRun, nextRun time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastRun, s.nextRun
+}
+
+func (s *scheduler) setLastRun(t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastRun = t
+}
+
+func (s *scheduler) setNextRun(t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextRun = t
+}
+
+// runScheduler refreshes the feeds that are due immediately and then every
+// interval until ctx is cancelled. A manual trigger refreshes every feed.
+func (app *application) runScheduler(ctx context.Context) {
+	s := app.scheduler
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	s.setNextRun(time.Now())
+
+	for {
+		all := false
+		select {
+		case <-ctx.Done():
+			app.logger.Info("Stopping feed refresh scheduler")
+			return
+		case <-timer.C:
+		case <-s.trigger:
+			all = true
+		}
+
+		s.setNextRun(time.Time{})
+		err := app.refreshFeeds(ctx, all)
+		if err != nil {
+			app.logger.Error("Scheduled feed refresh failed", "error", err)
+		}
+		s.setLastRun(time.Now())
+
+		timer.Reset(s.interval)
+		s.setNextRun(time.Now().Add(s.interval))
+	}
 }
 
-func (app *application) refreshFeeds() error {
-	feeds, err := app.queries.GetFeeds(context.Background())
+// refreshFeeds fetches every feed whose next check is due, or every feed when
+// all is set. WebSub subscriptions about to expire are renewed first.
+func (app *application) refreshFeeds(ctx context.Context, all bool) error {
+	err := app.renewWebSubSubscriptions(ctx)
+	if err != nil {
+		app.logger.Error("Renewing WebSub subscriptions failed", "error", err)
+	}
+
+	var feeds []data.Feed
+	if all {
+		feeds, err = app.queries.GetFeeds(ctx)
+		feeds = slices.DeleteFunc(feeds, func(feed data.Feed) bool {
+			return feed.Disabled != 0
+		})
+	} else {
+		feeds, err = app.queries.GetDueFeeds(ctx, time.Now().UTC().Format(time.RFC3339))
+	}
 	if err != nil {
 		app.logger.Error("Failed to get feeds from database.")
 		return err
 	}
+	if len(feeds) == 0 {
+		return nil
+	}
 
 	app.logger.Info("Starting feed refresh", "feed_count", len(feeds))
 
@@ -33,7 +137,7 @@ func (app *application) refreshFeeds() error {
 	// Start the workers
 	for range app.workers {
 		wg.Add(1)
-		go worker(taskChan, resultChan, &wg)
+		go worker(ctx, app.fetcher, taskChan, resultChan, &wg)
 	}
 	// Add feeds to the task channel for processing
 	go func() {
@@ -52,58 +156,253 @@ func (app *application) refreshFeeds() error {
 	successCount := 0
 	errorCount := 0
 	for result := range resultChan {
-		if result.err != nil {
+		if result.err != nil && !errors.Is(result.err, syndication.ErrNotModified) {
 			app.logger.Error("Fetching feed failed",
-				"feed_title", result.feedTitle,
+				"feed_title", result.feed.Title,
 				"error", result.err,
 			)
-			errorCount++
-			continue
-		}
-
-		now := time.Now().UTC().Format(time.RFC3339)
-		if len(result.entries) > 0 {
-			entries := buildCreateEntryParams(result.feedID, now, result.entries)
-			err = app.queries.CreateMultipleEntry(context.Background(), entries)
+			err = app.recordFetchError(context.Background(), result.feed, result.err)
 			if err != nil {
-				app.logger.Error("Fetching feed failed",
-					"feed_title", result.feedTitle,
-					"entry_count", len(result.entries),
-					"error", result.err,
+				app.logger.Error("Recording feed error failed",
+					"feed_title", result.feed.Title,
+					"error", err,
 				)
-				errorCount++
-				continue
 			}
+			errorCount++
+			continue
 		}
-		err = app.queries.UpdateFeedCheckedAt(context.Background(), data.UpdateFeedCheckedAtParams{
-			ID:        result.feedID,
-			CheckedAt: now,
-		})
+
+		err = app.storeFetchedFeed(context.Background(), result.feed, result.fetched, result.err)
 		if err != nil {
-			log.Println("Error updating feed checked timestamp", result.feedID, err)
-			app.logger.Error("Error updating feed checked timestamp",
-				"feed_title", result.feedTitle,
-				"error", result.err,
+			app.logger.Error("Storing feed failed",
+				"feed_title", result.feed.Title,
+				"error", err,
 			)
+			errorCount++
 			continue
 		}
+
+		entryCount := 0
+		if result.fetched != nil {
+			entryCount = len(result.fetched.Entries)
+		}
 		app.logger.Info("Successfully updated feed",
-			"feed_title", result.feedTitle,
-			"new_entries", len(result.entries))
+			"feed_title", result.feed.Title,
+			"entries", entryCount)
 		successCount++
 	}
+	app.logger.Info("Finished feed refresh", "updated", successCount, "failed", errorCount)
 	return nil
 }
 
-func worker(tc chan data.Feed, rc chan Result, wg *sync.WaitGroup) {
+// storeFetchedFeed saves the entries of a fetched feed and schedules its next
+// check. A nil fetched feed means the server reported no changes, with
+// fetchErr, in which case the stored validators and update hints are kept.
+func (app *application) storeFetchedFeed(ctx context.Context, feed data.Feed, fetched *syndication.Feed, fetchErr error) error {
+	now := time.Now().UTC()
+	validators := syndication.CacheValidators{
+		ETag:         feed.Etag,
+		LastModified: feed.LastModified,
+	}
+	hints := feed.UpdateHints
+
+	var movedTo string
+	var notModified *syndication.NotModifiedError
+	switch {
+	case fetched != nil:
+		movedTo = fetched.MovedTo
+	case errors.As(fetchErr, &notModified):
+		movedTo = notModified.MovedTo
+	}
+	if movedTo != "" && movedTo != feed.FeedUrl {
+		err := app.moveFeed(ctx, feed, movedTo)
+		if err != nil {
+			return err
+		}
+	}
+
+	if fetched != nil {
+		err := app.storeEntries(ctx, feed.ID, now.Format(time.RFC3339), fetched.Entries)
+		if err != nil {
+			return err
+		}
+		if feed.FetchFullContent != 0 {
+			err = app.fetchFullContent(ctx, feed, now.Format(time.RFC3339))
+			if err != nil {
+				return err
+			}
+		}
+		validators = fetched.CacheValidators
+		hints = fetched.UpdateHints
+	}
+
+	var iconURL string
+	if fetched != nil {
+		iconURL = fetched.IconURL
+	}
+	err := app.refreshFeedIcon(ctx, feed, iconURL, now)
+	if err != nil {
+		app.logger.Warn("Fetching feed icon failed", "feed_title", feed.Title, "error", err)
+	}
+
+	err = app.ensureWebSub(ctx, feed, fetched, now)
+	if err != nil {
+		app.logger.Warn("Subscribing to WebSub hub failed", "feed_title", feed.Title, "error", err)
+	}
+
+	recent, err := app.queries.CountRecentFeedEntries(ctx, data.CountRecentFeedEntriesParams{
+		FeedID:      feed.ID,
+		PublishedAt: now.Add(-postingWindow).Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+
+	nextCheck := app.nextCheckAt(now, hints, recent)
+	pushed, err := app.hasActiveWebSub(ctx, feed.ID, now)
+	if err != nil {
+		return err
+	}
+	if pushed {
+		// Updates arrive through the hub, so polling is only a fallback in
+		// case pushes go missing.
+		nextCheck = now.Add(app.maxFeedInterval)
+	}
+
+	return app.queries.UpdateFeedCheckedAt(ctx, data.UpdateFeedCheckedAtParams{
+		ID:           feed.ID,
+		CheckedAt:    now.Format(time.RFC3339),
+		Etag:         validators.ETag,
+		LastModified: validators.LastModified,
+		NextCheckAt:  nextCheck.Format(time.RFC3339),
+		UpdateHints:  hints,
+	})
+}
+
+// moveFeed points a permanently redirected feed at its new address and keeps
+// the old one as an alias so that subscribing to it again is still caught as
+// a duplicate. The move is skipped when another feed already owns the new
+// address.
+func (app *application) moveFeed(ctx context.Context, feed data.Feed, newURL string) error {
+	exists, err := app.queries.FeedURLExists(ctx, data.FeedURLExistsParams{
+		FeedUrl: newURL,
+		Url:     newURL,
+	})
+	if err != nil {
+		return err
+	}
+	if exists != 0 {
+		app.logger.Warn("Feed moved to an address that is already subscribed",
+			"feed_title", feed.Title,
+			"feed_url", feed.FeedUrl,
+			"new_url", newURL,
+		)
+		return nil
+	}
+
+	err = app.queries.CreateFeedAlias(ctx, data.CreateFeedAliasParams{
+		FeedID:    feed.ID,
+		Url:       feed.FeedUrl,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+
+	err = app.queries.UpdateFeedURL(ctx, data.UpdateFeedURLParams{
+		ID:      feed.ID,
+		FeedUrl: newURL,
+	})
+	if err != nil {
+		return err
+	}
+
+	app.logger.Info("Feed moved permanently",
+		"feed_title", feed.Title,
+		"feed_url", feed.FeedUrl,
+		"new_url", newURL,
+	)
+	return nil
+}
+
+// recordFetchError stores a failed fetch and backs off exponentially from
+// the minimum feed interval. The feed is disabled once it has failed
+// maxFeedErrors times in a row, or straight away when the server reports it
+// gone.
+func (app *application) recordFetchError(ctx context.Context, feed data.Feed, fetchErr error) error {
+	now := time.Now().UTC()
+	errorCount := feed.ErrorCount + 1
+
+	backoff := app.minFeedInterval
+	for i := int64(1); i < errorCount && backoff < app.maxFeedInterval; i++ {
+		backoff *= 2
+	}
+	backoff = min(backoff, app.maxFeedInterval)
+
+	var disabled int64
+	if errorCount >= int64(app.maxFeedErrors) || errors.Is(fetchErr, syndication.ErrFeedGone) {
+		disabled = 1
+		app.logger.Warn("Disabling feed", "feed_title", feed.Title, "error_count", errorCount)
+	}
+
+	return app.queries.RecordFeedError(ctx, data.RecordFeedErrorParams{
+		ID:          feed.ID,
+		LastError:   fetchErr.Error(),
+		LastErrorAt: now.Format(time.RFC3339),
+		NextCheckAt: now.Add(backoff).Format(time.RFC3339),
+		Disabled:    disabled,
+	})
+}
+
+// nextCheckAt picks when a feed should next be fetched. The interval follows
+// the feed's posting rate over the last postingWindow, is never shorter than
+// what the publisher asks for through ttl or sy:updatePeriod, and is bounded
+// by the configured minimum and maximum. Hours and days the publisher asks
+// us to skip are then stepped over.
+func (app *application) nextCheckAt(now time.Time, hints syndication.UpdateHints, recentEntries int64) time.Time {
+	interval := app.maxFeedInterval
+	if recentEntries > 0 {
+		interval = postingWindow / time.Duration(recentEntries)
+	}
+	interval = max(interval, hints.TTL, hints.UpdatePeriod)
+	interval = min(max(interval, app.minFeedInterval), app.maxFeedInterval)
+
+	next := now.Add(interval).UTC()
+	for range 7 * 24 {
+		if !slices.Contains(hints.SkipHours, next.Hour()) && !slices.Contains(hints.SkipDays, next.Weekday()) {
+			break
+		}
+		next = next.Truncate(time.Hour).Add(time.Hour)
+	}
+	return next
+}
+
+func worker(ctx context.Context, fetcher *syndication.Fetcher, tc chan data.Feed, rc chan Result, wg *sync.WaitGroup) {
 	defer wg.Done()
 	for feed := range tc {
-		entries, err := syndication.GetNewEntries(feed.FeedUrl, feed.Type, feed.CheckedAt)
+		fetched, err := fetchFeed(ctx, fetcher, feed)
 		rc <- Result{
-			feedID:    feed.ID,
-			feedTitle: feed.Title,
-			entries:   entries,
-			err:       err,
+			feed:    feed,
+			fetched: fetched,
+			err:     err,
 		}
 	}
 }
+
+// fetchFeed fetches a stored feed conditionally on its validators and with
+// its request options. Scraper feeds are built from their page with the
+// stored rules.
+func fetchFeed(ctx context.Context, fetcher *syndication.Fetcher, feed data.Feed) (*syndication.Feed, error) {
+	fetcher, err := fetcher.WithOptions(feed.RequestOptions, feed.FeedUrl)
+	if err != nil {
+		return nil, err
+	}
+	cv := syndication.CacheValidators{
+		ETag:         feed.Etag,
+		LastModified: feed.LastModified,
+	}
+	if feed.Type == syndication.Scraper {
+		return fetcher.Scrape(ctx, feed.FeedUrl, feed.ScraperRules, cv)
+	}
+	return fetcher.GetNewEntries(ctx, feed.FeedUrl, feed.Type, cv)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/oahshtsua/sammler/internal/syndication"
+)
+
+func TestStoreFetchedFeedKeepsUpdateHints(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprint(w, `<rss version="2.0"><channel><title>Blog</title><ttl>180</ttl><item><guid>1</guid></item></channel></rss>`)
+	}))
+	defer srv.Close()
+
+	app, _ := newTestApplication(t)
+	feed := createTestFeed(t, app, syndication.RSS, srv.URL)
+
+	for i := range 2 {
+		fetched, err := fetchFeed(t.Context(), app.fetcher, feed)
+		if i == 1 && !errors.Is(err, syndication.ErrNotModified) {
+			t.Fatalf("second fetch: got %v, want ErrNotModified", err)
+		} else if i == 0 && err != nil {
+			t.Fatal(err)
+		}
+		err = app.storeFetchedFeed(t.Context(), feed, fetched, err)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		feed, err = app.queries.GetFeed(t.Context(), feed.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if feed.UpdateHints.TTL != 3*time.Hour {
+			t.Errorf("fetch %d: TTL = %v, want 3h", i+1, feed.UpdateHints.TTL)
+		}
+		checkedAt, _ := time.Parse(time.RFC3339, feed.CheckedAt)
+		nextCheck, _ := time.Parse(time.RFC3339, feed.NextCheckAt)
+		if d := nextCheck.Sub(checkedAt); d < 3*time.Hour {
+			t.Errorf("fetch %d: next check in %v, want at least the TTL", i+1, d)
+		}
+	}
+}
+
+func TestStoreFetchedFeedMovedAndNotModified(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/old":
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+		case r.Header.Get("If-None-Match") == `"v1"`:
+			w.WriteHeader(http.StatusNotModified)
+		default:
+			w.Header().Set("Content-Type", "application/rss+xml")
+			fmt.Fprint(w, `<rss version="2.0"><channel><title>Blog</title></channel></rss>`)
+		}
+	}))
+	defer srv.Close()
+
+	app, db := newTestApplication(t)
+	feed := createTestFeed(t, app, syndication.RSS, srv.URL+"/old")
+	_, err := db.Exec(`UPDATE feeds SET etag = '"v1"' WHERE id = ?`, feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err = app.queries.GetFeed(t.Context(), feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fetched, err := fetchFeed(t.Context(), app.fetcher, feed)
+	if !errors.Is(err, syndication.ErrNotModified) {
+		t.Fatalf("got %v, want ErrNotModified", err)
+	}
+	err = app.storeFetchedFeed(t.Context(), feed, fetched, err)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feed, err = app.queries.GetFeed(t.Context(), feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := srv.URL + "/new"; feed.FeedUrl != want {
+		t.Errorf("feed URL = %q, want %q", feed.FeedUrl, want)
+	}
+}
@@ -11,4 +11,5 @@ require (
 require (
 	github.com/aymerick/douceur v0.2.0 // indirect
 	github.com/gorilla/css v1.0.1 // indirect
+	golang.org/x/text v0.26.0 // indirect
 )
@@ -6,31 +6,70 @@ import (
 	"strings"
 )
 
-func (q *Queries) CreateMultipleEntry(ctx context.Context, args []CreateEntryParams) error {
+// CreateMultipleEntry upserts the entries and returns their IDs by GUID.
+func (q *Queries) CreateMultipleEntry(ctx context.Context, args []CreateEntryParams) (map[string]int64, error) {
+	ids := make(map[string]int64, len(args))
+	if len(args) == 0 {
+		return ids, nil
+	}
+
 	baseQuery := `INSERT INTO entries (
 	feed_id,
+	guid,
 	title,
 	author,
 	content,
 	external_url,
 	published_at,
-	created_at
+	created_at,
+	thumbnail_url,
+	summary
 	) VALUES`
+	conflictClause := `ON CONFLICT (feed_id, guid) DO UPDATE
+	SET title = excluded.title,
+	author = excluded.author,
+	content = excluded.content,
+	external_url = excluded.external_url,
+	published_at = excluded.published_at,
+	thumbnail_url = excluded.thumbnail_url,
+	summary = excluded.summary
+	RETURNING guid, id`
 
 	placeholders := []string{}
 	arguments := []any{}
 
 	for _, arg := range args {
-		placeholders = append(placeholders, "(?, ?, ?, ?, ?, ?, ?)")
+		placeholders = append(placeholders, "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
 		arguments = append(arguments, arg.FeedID)
+		arguments = append(arguments, arg.Guid)
 		arguments = append(arguments, arg.Title)
 		arguments = append(arguments, arg.Author)
 		arguments = append(arguments, arg.Content)
 		arguments = append(arguments, arg.ExternalUrl)
 		arguments = append(arguments, arg.PublishedAt)
 		arguments = append(arguments, arg.CreatedAt)
+		arguments = append(arguments, arg.ThumbnailUrl)
+		arguments = append(arguments, arg.Summary)
+	}
+	finalQuery := fmt.Sprintf("%s %s %s;", baseQuery, strings.Join(placeholders, ","), conflictClause)
+	rows, err := q.db.QueryContext(ctx, finalQuery, arguments...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var guid string
+		var id int64
+		if err := rows.Scan(&guid, &id); err != nil {
+			return nil, err
+		}
+		ids[guid] = id
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
-	finalQuery := fmt.Sprintf("%s %s;", baseQuery, strings.Join(placeholders, ","))
-	_, err := q.db.ExecContext(ctx, finalQuery, arguments...)
-	return err
+	return ids, nil
 }
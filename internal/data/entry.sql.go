@@ -13,41 +13,76 @@ import (
 const createEntry = `-- name: CreateEntry :exec
 INSERT INTO entries (
     feed_id,
+    guid,
     title,
     author,
     content,
     external_url,
     published_at,
-    created_at
+    created_at,
+    thumbnail_url,
+    summary
 )
 VALUES (
-    ?, ?, ?, ?, ?, ?, ?
+    ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
 )
+ON CONFLICT (feed_id, guid) DO UPDATE
+SET title = excluded.title,
+    author = excluded.author,
+    content = excluded.content,
+    external_url = excluded.external_url,
+    published_at = excluded.published_at,
+    thumbnail_url = excluded.thumbnail_url,
+    summary = excluded.summary
 `
 
 type CreateEntryParams struct {
-	FeedID      int64
-	Title       string
-	Author      sql.NullString
-	Content     string
-	ExternalUrl string
-	PublishedAt string
-	CreatedAt   string
+	FeedID       int64
+	Guid         string
+	Title        string
+	Author       sql.NullString
+	Content      string
+	ExternalUrl  string
+	PublishedAt  string
+	CreatedAt    string
+	ThumbnailUrl string
+	Summary      string
 }
 
 func (q *Queries) CreateEntry(ctx context.Context, arg CreateEntryParams) error {
 	_, err := q.db.ExecContext(ctx, createEntry,
 		arg.FeedID,
+		arg.Guid,
 		arg.Title,
 		arg.Author,
 		arg.Content,
 		arg.ExternalUrl,
 		arg.PublishedAt,
 		arg.CreatedAt,
+		arg.ThumbnailUrl,
+		arg.Summary,
 	)
 	return err
 }
 
+const countRecentFeedEntries = `-- name: CountRecentFeedEntries :one
+SELECT COUNT(*)
+FROM entries
+WHERE feed_id = ? AND published_at >= ?
+`
+
+type CountRecentFeedEntriesParams struct {
+	FeedID      int64
+	PublishedAt string
+}
+
+func (q *Queries) CountRecentFeedEntries(ctx context.Context, arg CountRecentFeedEntriesParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countRecentFeedEntries, arg.FeedID, arg.PublishedAt)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const deleteEntry = `-- name: DeleteEntry :exec
 DELETE FROM entries
 WHERE id = ?
@@ -58,8 +93,57 @@ func (q *Queries) DeleteEntry(ctx context.Context, id int64) error {
 	return err
 }
 
+const deleteFeedEntries = `-- name: DeleteFeedEntries :exec
+DELETE FROM entries
+WHERE feed_id = ?
+`
+
+func (q *Queries) DeleteFeedEntries(ctx context.Context, feedID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteFeedEntries, feedID)
+	return err
+}
+
+const getEntriesWithoutFullContent = `-- name: GetEntriesWithoutFullContent :many
+SELECT id, external_url
+FROM entries
+WHERE feed_id = ? AND created_at >= ? AND full_content = '' AND external_url != ''
+`
+
+type GetEntriesWithoutFullContentParams struct {
+	FeedID    int64
+	CreatedAt string
+}
+
+type GetEntriesWithoutFullContentRow struct {
+	ID          int64
+	ExternalUrl string
+}
+
+func (q *Queries) GetEntriesWithoutFullContent(ctx context.Context, arg GetEntriesWithoutFullContentParams) ([]GetEntriesWithoutFullContentRow, error) {
+	rows, err := q.db.QueryContext(ctx, getEntriesWithoutFullContent, arg.FeedID, arg.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetEntriesWithoutFullContentRow
+	for rows.Next() {
+		var i GetEntriesWithoutFullContentRow
+		if err := rows.Scan(&i.ID, &i.ExternalUrl); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getEntry = `-- name: GetEntry :one
-SELECT feeds.title as feed_title, entries.id, entries.feed_id, entries.title, entries.author, entries.content, entries.external_url, entries.published_at, entries.read, entries.starred, entries.created_at
+SELECT feeds.title as feed_title, entries.id, entries.feed_id, entries.title, entries.author, entries.content, entries.external_url, entries.published_at, entries.read, entries.starred, entries.created_at, entries.guid, entries.thumbnail_url, entries.summary, entries.full_content
 FROM entries
 JOIN feeds
     ON entries.feed_id = feeds.id
@@ -67,17 +151,21 @@ WHERE entries.id = ?
 `
 
 type GetEntryRow struct {
-	FeedTitle   string
-	ID          int64
-	FeedID      int64
-	Title       string
-	Author      sql.NullString
-	Content     string
-	ExternalUrl string
-	PublishedAt string
-	Read        int64
-	Starred     int64
-	CreatedAt   string
+	FeedTitle    string
+	ID           int64
+	FeedID       int64
+	Title        string
+	Author       sql.NullString
+	Content      string
+	ExternalUrl  string
+	PublishedAt  string
+	Read         int64
+	Starred      int64
+	CreatedAt    string
+	Guid         string
+	ThumbnailUrl string
+	Summary      string
+	FullContent  string
 }
 
 func (q *Queries) GetEntry(ctx context.Context, id int64) (GetEntryRow, error) {
@@ -95,12 +183,52 @@ func (q *Queries) GetEntry(ctx context.Context, id int64) (GetEntryRow, error) {
 		&i.Read,
 		&i.Starred,
 		&i.CreatedAt,
+		&i.Guid,
+		&i.ThumbnailUrl,
+		&i.Summary,
+		&i.FullContent,
 	)
 	return i, err
 }
 
+const getEntryIDByGuid = `-- name: GetEntryIDByGuid :one
+SELECT id
+FROM entries
+WHERE feed_id = ? AND guid = ?
+`
+
+type GetEntryIDByGuidParams struct {
+	FeedID int64
+	Guid   string
+}
+
+func (q *Queries) GetEntryIDByGuid(ctx context.Context, arg GetEntryIDByGuidParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getEntryIDByGuid, arg.FeedID, arg.Guid)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const getEntryPublishedAt = `-- name: GetEntryPublishedAt :one
+SELECT published_at
+FROM entries
+WHERE feed_id = ? AND guid = ?
+`
+
+type GetEntryPublishedAtParams struct {
+	FeedID int64
+	Guid   string
+}
+
+func (q *Queries) GetEntryPublishedAt(ctx context.Context, arg GetEntryPublishedAtParams) (string, error) {
+	row := q.db.QueryRowContext(ctx, getEntryPublishedAt, arg.FeedID, arg.Guid)
+	var published_at string
+	err := row.Scan(&published_at)
+	return published_at, err
+}
+
 const getFeedEntries = `-- name: GetFeedEntries :many
-SELECT feeds.title as feed_title, entries.id, entries.feed_id, entries.title, entries.author, entries.content, entries.external_url, entries.published_at, entries.read, entries.starred, entries.created_at
+SELECT feeds.title as feed_title, entries.id, entries.feed_id, entries.title, entries.author, entries.content, entries.external_url, entries.published_at, entries.read, entries.starred, entries.created_at, entries.guid, entries.thumbnail_url, entries.summary, entries.full_content
 FROM entries
 JOIN feeds
     ON entries.feed_id = feeds.id
@@ -109,17 +237,21 @@ ORDER BY published_at DESC
 `
 
 type GetFeedEntriesRow struct {
-	FeedTitle   string
-	ID          int64
-	FeedID      int64
-	Title       string
-	Author      sql.NullString
-	Content     string
-	ExternalUrl string
-	PublishedAt string
-	Read        int64
-	Starred     int64
-	CreatedAt   string
+	FeedTitle    string
+	ID           int64
+	FeedID       int64
+	Title        string
+	Author       sql.NullString
+	Content      string
+	ExternalUrl  string
+	PublishedAt  string
+	Read         int64
+	Starred      int64
+	CreatedAt    string
+	Guid         string
+	ThumbnailUrl string
+	Summary      string
+	FullContent  string
 }
 
 func (q *Queries) GetFeedEntries(ctx context.Context, feedID int64) ([]GetFeedEntriesRow, error) {
@@ -143,6 +275,10 @@ func (q *Queries) GetFeedEntries(ctx context.Context, feedID int64) ([]GetFeedEn
 			&i.Read,
 			&i.Starred,
 			&i.CreatedAt,
+			&i.Guid,
+			&i.ThumbnailUrl,
+			&i.Summary,
+			&i.FullContent,
 		); err != nil {
 			return nil, err
 		}
@@ -157,8 +293,37 @@ func (q *Queries) GetFeedEntries(ctx context.Context, feedID int64) ([]GetFeedEn
 	return items, nil
 }
 
+const getLegacyEntryLinks = `-- name: GetLegacyEntryLinks :many
+SELECT external_url
+FROM entries
+WHERE feed_id = ? AND guid = external_url
+`
+
+func (q *Queries) GetLegacyEntryLinks(ctx context.Context, feedID int64) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getLegacyEntryLinks, feedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var external_url string
+		if err := rows.Scan(&external_url); err != nil {
+			return nil, err
+		}
+		items = append(items, external_url)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getUnreadEntries = `-- name: GetUnreadEntries :many
-SELECT feeds.title AS feed_title, entries.id, entries.feed_id, entries.title, entries.author, entries.content, entries.external_url, entries.published_at, entries.read, entries.starred, entries.created_at
+SELECT feeds.title AS feed_title, entries.id, entries.feed_id, entries.title, entries.author, entries.content, entries.external_url, entries.published_at, entries.read, entries.starred, entries.created_at, entries.guid, entries.thumbnail_url, entries.summary, entries.full_content
 FROM entries
 JOIN feeds
     ON entries.feed_id = feeds.id
@@ -167,17 +332,21 @@ ORDER BY published_at DESC
 `
 
 type GetUnreadEntriesRow struct {
-	FeedTitle   string
-	ID          int64
-	FeedID      int64
-	Title       string
-	Author      sql.NullString
-	Content     string
-	ExternalUrl string
-	PublishedAt string
-	Read        int64
-	Starred     int64
-	CreatedAt   string
+	FeedTitle    string
+	ID           int64
+	FeedID       int64
+	Title        string
+	Author       sql.NullString
+	Content      string
+	ExternalUrl  string
+	PublishedAt  string
+	Read         int64
+	Starred      int64
+	CreatedAt    string
+	Guid         string
+	ThumbnailUrl string
+	Summary      string
+	FullContent  string
 }
 
 func (q *Queries) GetUnreadEntries(ctx context.Context) ([]GetUnreadEntriesRow, error) {
@@ -201,6 +370,10 @@ func (q *Queries) GetUnreadEntries(ctx context.Context) ([]GetUnreadEntriesRow,
 			&i.Read,
 			&i.Starred,
 			&i.CreatedAt,
+			&i.Guid,
+			&i.ThumbnailUrl,
+			&i.Summary,
+			&i.FullContent,
 		); err != nil {
 			return nil, err
 		}
@@ -236,3 +409,36 @@ func (q *Queries) MarkEntryRead(ctx context.Context, id int64) error {
 	_, err := q.db.ExecContext(ctx, markEntryRead, id)
 	return err
 }
+
+const updateEntryFullContent = `-- name: UpdateEntryFullContent :exec
+UPDATE entries
+SET full_content = ?
+WHERE id = ?
+`
+
+type UpdateEntryFullContentParams struct {
+	FullContent string
+	ID          int64
+}
+
+func (q *Queries) UpdateEntryFullContent(ctx context.Context, arg UpdateEntryFullContentParams) error {
+	_, err := q.db.ExecContext(ctx, updateEntryFullContent, arg.FullContent, arg.ID)
+	return err
+}
+
+const updateLegacyEntryGuid = `-- name: UpdateLegacyEntryGuid :exec
+UPDATE OR IGNORE entries
+SET guid = ?
+WHERE feed_id = ? AND external_url = ? AND guid = external_url
+`
+
+type UpdateLegacyEntryGuidParams struct {
+	Guid        string
+	FeedID      int64
+	ExternalUrl string
+}
+
+func (q *Queries) UpdateLegacyEntryGuid(ctx context.Context, arg UpdateLegacyEntryGuidParams) error {
+	_, err := q.db.ExecContext(ctx, updateLegacyEntryGuid, arg.Guid, arg.FeedID, arg.ExternalUrl)
+	return err
+}
@@ -20,20 +20,32 @@ INSERT INTO feeds (
     site_url,
     type,
     updated_at,
-    checked_at
+    checked_at,
+    etag,
+    last_modified,
+    next_check_at,
+    scraper_rules,
+    request_options,
+    update_hints
 )
-VALUES (?, ?, ?, ?, ?, ?, ?)
-RETURNING id, title, subtitle, feed_url, site_url, type, disabled, checked_at, updated_at
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING id, title, subtitle, feed_url, site_url, type, disabled, checked_at, updated_at, etag, last_modified, next_check_at, error_count, last_error, last_error_at, fetch_full_content, scraper_rules, request_options, update_hints
 `
 
 type CreateFeedParams struct {
-	Title     string
-	Subtitle  sql.NullString
-	FeedUrl   string
-	SiteUrl   string
-	Type      syndication.FeedType
-	UpdatedAt string
-	CheckedAt string
+	Title          string
+	Subtitle       sql.NullString
+	FeedUrl        string
+	SiteUrl        string
+	Type           syndication.FeedType
+	UpdatedAt      string
+	CheckedAt      string
+	Etag           string
+	LastModified   string
+	NextCheckAt    string
+	ScraperRules   syndication.ScraperRules
+	RequestOptions syndication.RequestOptions
+	UpdateHints    syndication.UpdateHints
 }
 
 func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, error) {
@@ -45,6 +57,12 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		arg.Type,
 		arg.UpdatedAt,
 		arg.CheckedAt,
+		arg.Etag,
+		arg.LastModified,
+		arg.NextCheckAt,
+		arg.ScraperRules,
+		arg.RequestOptions,
+		arg.UpdateHints,
 	)
 	var i Feed
 	err := row.Scan(
@@ -57,10 +75,41 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Disabled,
 		&i.CheckedAt,
 		&i.UpdatedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.NextCheckAt,
+		&i.ErrorCount,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.FetchFullContent,
+		&i.ScraperRules,
+		&i.RequestOptions,
+		&i.UpdateHints,
 	)
 	return i, err
 }
 
+const createFeedAlias = `-- name: CreateFeedAlias :exec
+INSERT INTO feed_aliases (
+    feed_id,
+    url,
+    created_at
+)
+VALUES (?, ?, ?)
+ON CONFLICT (url) DO NOTHING
+`
+
+type CreateFeedAliasParams struct {
+	FeedID    int64
+	Url       string
+	CreatedAt string
+}
+
+func (q *Queries) CreateFeedAlias(ctx context.Context, arg CreateFeedAliasParams) error {
+	_, err := q.db.ExecContext(ctx, createFeedAlias, arg.FeedID, arg.Url, arg.CreatedAt)
+	return err
+}
+
 const deleteFeed = `-- name: DeleteFeed :exec
 DELETE
 FROM feeds
@@ -72,8 +121,108 @@ func (q *Queries) DeleteFeed(ctx context.Context, id int64) error {
 	return err
 }
 
+const deleteFeedAliases = `-- name: DeleteFeedAliases :exec
+DELETE
+FROM feed_aliases
+WHERE feed_id = ?
+`
+
+func (q *Queries) DeleteFeedAliases(ctx context.Context, feedID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteFeedAliases, feedID)
+	return err
+}
+
+const enableFeed = `-- name: EnableFeed :exec
+UPDATE feeds
+SET disabled = 0,
+    error_count = 0,
+    last_error = '',
+    last_error_at = '',
+    next_check_at = ''
+WHERE id = ?
+`
+
+func (q *Queries) EnableFeed(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, enableFeed, id)
+	return err
+}
+
+const feedURLExists = `-- name: FeedURLExists :one
+SELECT EXISTS (
+    SELECT 1
+    FROM feeds
+    WHERE feeds.feed_url = ?
+    UNION ALL
+    SELECT 1
+    FROM feed_aliases
+    WHERE feed_aliases.url = ?
+) AS url_exists
+`
+
+type FeedURLExistsParams struct {
+	FeedUrl string
+	Url     string
+}
+
+func (q *Queries) FeedURLExists(ctx context.Context, arg FeedURLExistsParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, feedURLExists, arg.FeedUrl, arg.Url)
+	var url_exists int64
+	err := row.Scan(&url_exists)
+	return url_exists, err
+}
+
+const getDueFeeds = `-- name: GetDueFeeds :many
+SELECT id, title, subtitle, feed_url, site_url, type, disabled, checked_at, updated_at, etag, last_modified, next_check_at, error_count, last_error, last_error_at, fetch_full_content, scraper_rules, request_options, update_hints
+FROM feeds
+WHERE next_check_at <= ? AND disabled = 0
+ORDER BY next_check_at
+`
+
+func (q *Queries) GetDueFeeds(ctx context.Context, nextCheckAt string) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getDueFeeds, nextCheckAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Subtitle,
+			&i.FeedUrl,
+			&i.SiteUrl,
+			&i.Type,
+			&i.Disabled,
+			&i.CheckedAt,
+			&i.UpdatedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.NextCheckAt,
+			&i.ErrorCount,
+			&i.LastError,
+			&i.LastErrorAt,
+			&i.FetchFullContent,
+			&i.ScraperRules,
+			&i.RequestOptions,
+			&i.UpdateHints,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getFeed = `-- name: GetFeed :one
-SELECT id, title, subtitle, feed_url, site_url, type, disabled, checked_at, updated_at
+SELECT id, title, subtitle, feed_url, site_url, type, disabled, checked_at, updated_at, etag, last_modified, next_check_at, error_count, last_error, last_error_at, fetch_full_content, scraper_rules, request_options, update_hints
 FROM feeds
 WHERE feeds.id = ?
 `
@@ -91,12 +240,22 @@ func (q *Queries) GetFeed(ctx context.Context, id int64) (Feed, error) {
 		&i.Disabled,
 		&i.CheckedAt,
 		&i.UpdatedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.NextCheckAt,
+		&i.ErrorCount,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.FetchFullContent,
+		&i.ScraperRules,
+		&i.RequestOptions,
+		&i.UpdateHints,
 	)
 	return i, err
 }
 
 const getFeeds = `-- name: GetFeeds :many
-SELECT id, title, subtitle, feed_url, site_url, type, disabled, checked_at, updated_at
+SELECT id, title, subtitle, feed_url, site_url, type, disabled, checked_at, updated_at, etag, last_modified, next_check_at, error_count, last_error, last_error_at, fetch_full_content, scraper_rules, request_options, update_hints
 FROM feeds
 ORDER BY title
 `
@@ -120,6 +279,16 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 			&i.Disabled,
 			&i.CheckedAt,
 			&i.UpdatedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.NextCheckAt,
+			&i.ErrorCount,
+			&i.LastError,
+			&i.LastErrorAt,
+			&i.FetchFullContent,
+			&i.ScraperRules,
+			&i.RequestOptions,
+			&i.UpdateHints,
 		); err != nil {
 			return nil, err
 		}
@@ -145,18 +314,113 @@ func (q *Queries) MarkFeedRead(ctx context.Context, feedID int64) error {
 	return err
 }
 
+const recordFeedError = `-- name: RecordFeedError :exec
+UPDATE feeds
+SET error_count = error_count + 1,
+    last_error = ?,
+    last_error_at = ?,
+    next_check_at = ?,
+    disabled = ?
+WHERE id = ?
+`
+
+type RecordFeedErrorParams struct {
+	LastError   string
+	LastErrorAt string
+	NextCheckAt string
+	Disabled    int64
+	ID          int64
+}
+
+func (q *Queries) RecordFeedError(ctx context.Context, arg RecordFeedErrorParams) error {
+	_, err := q.db.ExecContext(ctx, recordFeedError,
+		arg.LastError,
+		arg.LastErrorAt,
+		arg.NextCheckAt,
+		arg.Disabled,
+		arg.ID,
+	)
+	return err
+}
+
+const setFeedFetchFullContent = `-- name: SetFeedFetchFullContent :exec
+UPDATE feeds
+SET fetch_full_content = ?
+WHERE id = ?
+`
+
+type SetFeedFetchFullContentParams struct {
+	FetchFullContent int64
+	ID               int64
+}
+
+func (q *Queries) SetFeedFetchFullContent(ctx context.Context, arg SetFeedFetchFullContentParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedFetchFullContent, arg.FetchFullContent, arg.ID)
+	return err
+}
+
+const setFeedRequestOptions = `-- name: SetFeedRequestOptions :exec
+UPDATE feeds
+SET request_options = ?
+WHERE id = ?
+`
+
+type SetFeedRequestOptionsParams struct {
+	RequestOptions syndication.RequestOptions
+	ID             int64
+}
+
+func (q *Queries) SetFeedRequestOptions(ctx context.Context, arg SetFeedRequestOptionsParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedRequestOptions, arg.RequestOptions, arg.ID)
+	return err
+}
+
 const updateFeedCheckedAt = `-- name: UpdateFeedCheckedAt :exec
 UPDATE feeds
-SET checked_at = ?
+SET checked_at = ?,
+    etag = ?,
+    last_modified = ?,
+    next_check_at = ?,
+    update_hints = ?,
+    error_count = 0,
+    last_error = '',
+    last_error_at = ''
 WHERE id = ?
 `
 
 type UpdateFeedCheckedAtParams struct {
-	CheckedAt string
-	ID        int64
+	CheckedAt    string
+	Etag         string
+	LastModified string
+	NextCheckAt  string
+	UpdateHints  syndication.UpdateHints
+	ID           int64
 }
 
 func (q *Queries) UpdateFeedCheckedAt(ctx context.Context, arg UpdateFeedCheckedAtParams) error {
-	_, err := q.db.ExecContext(ctx, updateFeedCheckedAt, arg.CheckedAt, arg.ID)
+	_, err := q.db.ExecContext(ctx, updateFeedCheckedAt,
+		arg.CheckedAt,
+		arg.Etag,
+		arg.LastModified,
+		arg.NextCheckAt,
+		arg.UpdateHints,
+		arg.ID,
+	)
+	return err
+}
+
+const updateFeedURL = `-- name: UpdateFeedURL :exec
+UPDATE feeds
+SET feed_url = ?
+WHERE id = ?
+`
+
+type UpdateFeedURLParams struct {
+	FeedUrl string
+	ID      int64
+}
+
+func (q *Queries) UpdateFeedURL(ctx context.Context, arg UpdateFeedURLParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedURL, arg.FeedUrl, arg.ID)
 	return err
 }
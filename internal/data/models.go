@@ -10,27 +10,89 @@ import (
 	"github.com/oahshtsua/sammler/internal/syndication"
 )
 
+type Enclosure struct {
+	ID               int64
+	EntryID          int64
+	Url              string
+	MimeType         string
+	Title            string
+	Length           int64
+	Duration         int64
+	Episode          string
+	ImageUrl         string
+	PlaybackPosition int64
+}
+
 type Entry struct {
-	ID          int64
-	FeedID      int64
-	Title       string
-	Author      sql.NullString
-	Content     string
-	ExternalUrl string
-	PublishedAt string
-	Read        int64
-	Starred     int64
-	CreatedAt   string
+	ID           int64
+	FeedID       int64
+	Title        string
+	Author       sql.NullString
+	Content      string
+	ExternalUrl  string
+	PublishedAt  string
+	Read         int64
+	Starred      int64
+	CreatedAt    string
+	Guid         string
+	ThumbnailUrl string
+	Summary      string
+	FullContent  string
+}
+
+type EntryTag struct {
+	EntryID int64
+	TagID   int64
 }
 
 type Feed struct {
+	ID               int64
+	Title            string
+	Subtitle         sql.NullString
+	FeedUrl          string
+	SiteUrl          string
+	Type             syndication.FeedType
+	Disabled         int64
+	CheckedAt        string
+	UpdatedAt        string
+	Etag             string
+	LastModified     string
+	NextCheckAt      string
+	ErrorCount       int64
+	LastError        string
+	LastErrorAt      string
+	FetchFullContent int64
+	ScraperRules     syndication.ScraperRules
+	RequestOptions   syndication.RequestOptions
+	UpdateHints      syndication.UpdateHints
+}
+
+type FeedAlias struct {
 	ID        int64
-	Title     string
-	Subtitle  sql.NullString
-	FeedUrl   string
-	SiteUrl   string
-	Type      syndication.FeedType
-	Disabled  int64
-	CheckedAt string
-	UpdatedAt string
+	FeedID    int64
+	Url       string
+	CreatedAt string
+}
+
+type FeedIcon struct {
+	FeedID    int64
+	Url       string
+	Content   []byte
+	Hash      string
+	FetchedAt string
+}
+
+type Tag struct {
+	ID   int64
+	Name string
+}
+
+type WebsubSubscription struct {
+	FeedID         int64
+	HubUrl         string
+	TopicUrl       string
+	Secret         string
+	State          string
+	LeaseExpiresAt string
+	RequestedAt    string
 }
@@ -1,58 +1,150 @@
 package syndication
 
-import "strings"
+import (
+	"html"
+	"strings"
+)
+
+// AtomText is an Atom text construct. Its body is plain text, escaped HTML,
+// or inline XHTML wrapped in a div depending on Type.
+type AtomText struct {
+	xmlBase
+	Type  string `xml:"type,attr"`
+	Text  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+// html returns the body of the text construct as HTML.
+func (at AtomText) html() string {
+	switch typ := strings.ToLower(strings.TrimSpace(at.Type)); {
+	case typ == "xhtml" || strings.HasSuffix(typ, "xhtml+xml"):
+		return unwrapXHTMLDiv(at.Inner)
+	case typ == "html" || strings.HasSuffix(typ, "/html"):
+		return strings.TrimSpace(at.Text)
+	default:
+		return html.EscapeString(strings.TrimSpace(at.Text))
+	}
+}
+
+// unwrapXHTMLDiv returns the children of the div that Atom requires around
+// inline XHTML content.
+func unwrapXHTMLDiv(inner string) string {
+	inner = strings.TrimSpace(inner)
+	start := strings.Index(inner, ">")
+	end := strings.LastIndex(inner, "</")
+	if !strings.HasPrefix(inner, "<") || start < 0 || end < start {
+		return inner
+	}
+	return strings.TrimSpace(inner[start+1 : end])
+}
 
 type AtomFeedEntry struct {
+	mediaModule
+	xmlBase
+	ID        string `xml:"id"`
 	Title     string `xml:"title"`
-	Subtitle  string `xml:"subtitle"`
 	Published string `xml:"published"`
 	Updated   string `xml:"updated"`
 	Author    struct {
 		Name string `xml:"name"`
 	} `xml:"author"`
-	Link    Link   `xml:"link"`
-	Content string `xml:"content"`
+	Links      []Link         `xml:"link"`
+	Summary    AtomText       `xml:"summary"`
+	Content    AtomText       `xml:"content"`
+	Categories []AtomCategory `xml:"category"`
+	iTunesModule
 }
 
-func (afe AtomFeedEntry) toFeedEntry() *FeedEntry {
-	return &FeedEntry{
+func (afe AtomFeedEntry) toFeedEntry(feedBase string) *FeedEntry {
+	var link string
+	var enclosures []Enclosure
+	for _, l := range afe.Links {
+		switch l.Rel {
+		case "", "alternate":
+			if link == "" {
+				link = l.Href
+			}
+		case "enclosure":
+			if l.Href == "" {
+				continue
+			}
+			enclosures = append(enclosures, Enclosure{
+				URL:    strings.TrimSpace(l.Href),
+				Type:   strings.TrimSpace(l.Type),
+				Title:  strings.TrimSpace(l.Title),
+				Length: parseLength(l.Length),
+			})
+		}
+	}
+	afe.iTunesModule.apply(enclosures)
+
+	summary := afe.Summary.html()
+	content := afe.Content.html()
+	if content == "" {
+		content = summary
+	}
+
+	fe := &FeedEntry{
+		ID:          strings.TrimSpace(afe.ID),
 		Title:       strings.TrimSpace(afe.Title),
-		Description: strings.TrimSpace(afe.Subtitle),
-		Published:   afe.Published,
-		Updated:     afe.Updated,
+		Description: summary,
+		Published:   normalizeDate(afe.Published, afe.Updated),
+		Updated:     normalizeDate(afe.Updated),
 		Author:      strings.TrimSpace(afe.Author.Name),
-		Link:        afe.Link.Href,
-		Content:     strings.TrimSpace(afe.Content),
+		Link:        link,
+		Content:     content,
+		Categories:  normalizeCategories(atomCategoryNames(afe.Categories)),
+		Enclosures:  enclosures,
+		base:        joinBase(feedBase, afe.Base, afe.Content.Base),
 	}
+	afe.mediaModule.apply(fe)
+	return fe
 }
 
 type AtomFeed struct {
-	Title   string          `xml:"title"`
-	Links   []Link          `xml:"link"`
-	Updated string          `xml:"updated"`
-	Entries []AtomFeedEntry `xml:"entry"`
+	Title      string          `xml:"title"`
+	Links      []Link          `xml:"link"`
+	Updated    string          `xml:"updated"`
+	Icon       string          `xml:"icon"`
+	Logo       string          `xml:"logo"`
+	Categories []AtomCategory  `xml:"category"`
+	Entries    []AtomFeedEntry `xml:"entry"`
+	xmlBase
+	syndicationModule
 }
 
 func (af AtomFeed) toFeed() *Feed {
 
-	var feedURL, siteURL string
+	var feedURL, siteURL, hubURL string
 	for _, link := range af.Links {
 		switch link.Rel {
 		case "self":
 			feedURL = link.Href
+		case "hub":
+			if hubURL == "" {
+				hubURL = strings.TrimSpace(link.Href)
+			}
 		default:
 			siteURL = link.Href
 		}
 	}
 	var entries []FeedEntry
 	for _, entry := range af.Entries {
-		entries = append(entries, *entry.toFeedEntry())
+		entries = append(entries, *entry.toFeedEntry(af.Base))
 	}
 	return &Feed{
-		Title:   af.Title,
-		FeedURL: feedURL,
-		SiteURL: siteURL,
-		Entries: entries,
-		Type:    Atom,
+		Title:    strings.TrimSpace(af.Title),
+		FeedURL:  feedURL,
+		SiteURL:  siteURL,
+		IconURL:  firstNonEmpty(af.Icon, af.Logo),
+		HubURL:   hubURL,
+		TopicURL: strings.TrimSpace(feedURL),
+		Entries:  entries,
+		Type:     Atom,
+		UpdateHints: UpdateHints{
+			UpdatePeriod: af.updatePeriod(),
+		},
+		base:       af.Base,
+		categories: normalizeCategories(atomCategoryNames(af.Categories)),
 	}
 }
@@ -0,0 +1,45 @@
+package syndication
+
+import "strings"
+
+// AtomCategory is an Atom category. Term identifies the category and Label,
+// when present, is its human-readable name.
+type AtomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr"`
+}
+
+func (ac AtomCategory) name() string {
+	if label := strings.TrimSpace(ac.Label); label != "" {
+		return label
+	}
+	return ac.Term
+}
+
+func atomCategoryNames(categories []AtomCategory) []string {
+	names := make([]string, 0, len(categories))
+	for _, category := range categories {
+		names = append(names, category.name())
+	}
+	return names
+}
+
+// normalizeCategories trims and collapses the whitespace of each category and
+// drops empty ones and those repeated in a different case, keeping the first
+// spelling.
+func normalizeCategories(categories ...[]string) []string {
+	var normalized []string
+	seen := map[string]bool{}
+	for _, list := range categories {
+		for _, category := range list {
+			category = strings.Join(strings.Fields(category), " ")
+			key := strings.ToLower(category)
+			if category == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			normalized = append(normalized, category)
+		}
+	}
+	return normalized
+}
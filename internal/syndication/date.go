@@ -0,0 +1,174 @@
+package syndication
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// zoneOffsets maps the time zone abbreviations seen in feeds to their
+// offsets. time.Parse only knows the offset of the local zone's abbreviation
+// and makes up a zero offset for every other one, so named zones are replaced
+// before parsing and no layout contains one.
+var zoneOffsets = map[string]string{
+	"UT":   "+0000",
+	"UTC":  "+0000",
+	"GMT":  "+0000",
+	"Z":    "+0000",
+	"EST":  "-0500",
+	"EDT":  "-0400",
+	"CST":  "-0600",
+	"CDT":  "-0500",
+	"MST":  "-0700",
+	"MDT":  "-0600",
+	"PST":  "-0800",
+	"PDT":  "-0700",
+	"AKST": "-0900",
+	"AKDT": "-0800",
+	"HST":  "-1000",
+	"BST":  "+0100",
+	"CET":  "+0100",
+	"CEST": "+0200",
+	"EET":  "+0200",
+	"EEST": "+0300",
+	"JST":  "+0900",
+	"AEST": "+1000",
+	"AEDT": "+1100",
+}
+
+// zoneOffsetPattern matches offsets written relative to a named zone, such as
+// "GMT+2" or "UTC-05:30".
+var zoneOffsetPattern = regexp.MustCompile(`^(?:GMT|UTC|UT)([+-])(\d{1,2})(?::?(\d{2}))?$`)
+
+var dateFormats = buildDateFormats()
+
+// buildDateFormats lists the layouts tried by parseDate. RFC 822 style dates
+// are combined from their day, time and zone parts since feeds drop or vary
+// each of them independently.
+func buildDateFormats() []string {
+	formats := []string{
+		time.RFC3339,
+		"2006-01-02T15:04:05Z0700",
+		"2006-01-02T15:04Z07:00",
+		"2006-01-02T15:04:05",
+		"2006-01-02 15:04:05Z07:00",
+		"2006-01-02 15:04:05 -0700",
+		"2006-01-02 15:04:05",
+		"2006-01-02",
+		// ANSIC, UnixDate and RubyDate with the weekday removed.
+		"Jan 2 15:04:05 2006",
+		"Jan 2 15:04:05 -0700 2006",
+	}
+	for _, day := range []string{"2 Jan 2006", "2 January 2006", "2 Jan 06", "Jan 2 2006", "January 2 2006"} {
+		for _, clock := range []string{" 15:04:05", " 15:04", ""} {
+			for _, zone := range []string{" -0700", " -07:00", ""} {
+				if clock == "" && zone != "" {
+					continue
+				}
+				formats = append(formats, day+clock+zone)
+			}
+		}
+	}
+	return formats
+}
+
+// parseDate parses a date in any of the formats commonly found in feeds and
+// returns it in UTC. Dates without a zone, or with a zone name that is not in
+// zoneOffsets, are taken to be in UTC.
+func parseDate(date string) (time.Time, error) {
+	value := normalizeDateString(date)
+	for _, format := range dateFormats {
+		t, err := time.Parse(format, value)
+		if err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("Unrecognized date format: %s", date)
+}
+
+// normalizeDate returns the first of the given dates that parses as a UTC
+// RFC 3339 string, or an empty string when none of them does.
+func normalizeDate(dates ...string) string {
+	for _, date := range dates {
+		if strings.TrimSpace(date) == "" {
+			continue
+		}
+		t, err := parseDate(date)
+		if err == nil {
+			return t.Format(time.RFC3339)
+		}
+	}
+	return ""
+}
+
+// normalizeDateString removes the parts of a date that vary between
+// publishers without carrying information: extra whitespace, commas and the
+// weekday name. Named zones are written as offsets, or dropped when their
+// offset is unknown.
+func normalizeDateString(date string) string {
+	fields := strings.Fields(strings.ReplaceAll(date, ",", " "))
+	if len(fields) == 0 {
+		return ""
+	}
+	if isWeekday(fields[0]) {
+		fields = fields[1:]
+	}
+	normalized := fields[:0]
+	for i, field := range fields {
+		if field == "Sept" {
+			field = "Sep"
+		}
+		if i > 0 {
+			upper := strings.ToUpper(field)
+			if offset, ok := zoneOffsets[upper]; ok {
+				field = offset
+			} else if m := zoneOffsetPattern.FindStringSubmatch(upper); m != nil {
+				hours, minutes := m[2], m[3]
+				if len(hours) == 1 {
+					hours = "0" + hours
+				}
+				if minutes == "" {
+					minutes = "00"
+				}
+				field = m[1] + hours + minutes
+			} else if isZoneName(field) {
+				continue
+			}
+		}
+		normalized = append(normalized, field)
+	}
+	return strings.Join(normalized, " ")
+}
+
+// isZoneName reports whether s looks like a time zone abbreviation such as
+// "IST" or "NZDT": two to five capital letters that are not a month or a
+// meridiem.
+func isZoneName(s string) bool {
+	if len(s) < 2 || len(s) > 5 || s == "AM" || s == "PM" {
+		return false
+	}
+	for _, c := range s {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	for month := time.January; month <= time.December; month++ {
+		name := strings.ToUpper(month.String())
+		if s == name || s == name[:3] || s == "SEPT" {
+			return false
+		}
+	}
+	return true
+}
+
+func isWeekday(s string) bool {
+	s = strings.ToLower(strings.TrimSuffix(s, "."))
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+		if s == name || s == name[:3] {
+			return true
+		}
+	}
+	return false
+}
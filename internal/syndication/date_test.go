@@ -0,0 +1,153 @@
+package syndication
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		date string
+		want string
+	}{
+		// RFC 3339 and ISO 8601 variants.
+		{"2024-03-05T14:07:09Z", "2024-03-05T14:07:09Z"},
+		{"2024-03-05T14:07:09+02:00", "2024-03-05T12:07:09Z"},
+		{"2024-03-05T14:07:09.123-05:00", "2024-03-05T19:07:09.123Z"},
+		{"2024-03-05T14:07:09+0200", "2024-03-05T12:07:09Z"},
+		{"2024-03-05T14:07+02:00", "2024-03-05T12:07:00Z"},
+		{"2024-03-05T14:07:09", "2024-03-05T14:07:09Z"},
+		{"2024-03-05 14:07:09+02:00", "2024-03-05T12:07:09Z"},
+		{"2024-03-05 14:07:09 +0200", "2024-03-05T12:07:09Z"},
+		{"2024-03-05 14:07:09", "2024-03-05T14:07:09Z"},
+		{"2024-03-05", "2024-03-05T00:00:00Z"},
+
+		// ANSIC, UnixDate and RubyDate.
+		{"Tue Mar  5 14:07:09 2024", "2024-03-05T14:07:09Z"},
+		{"Tue Mar  5 14:07:09 EST 2024", "2024-03-05T19:07:09Z"},
+		{"Tue Mar 05 14:07:09 -0500 2024", "2024-03-05T19:07:09Z"},
+
+		// RFC 822 and RFC 1123 with their common variations.
+		{"Tue, 05 Mar 2024 14:07:09 +0200", "2024-03-05T12:07:09Z"},
+		{"Tue, 05 Mar 2024 14:07:09 +02:00", "2024-03-05T12:07:09Z"},
+		{"Tue, 05 Mar 2024 14:07:09", "2024-03-05T14:07:09Z"},
+		{"Tue, 05 Mar 2024 14:07 +0200", "2024-03-05T12:07:00Z"},
+		{"Tue, 05 Mar 2024", "2024-03-05T00:00:00Z"},
+		{"05 Mar 24 14:07:09 +0000", "2024-03-05T14:07:09Z"},
+		{"5 March 2024 14:07:09 +0000", "2024-03-05T14:07:09Z"},
+		{"Mar 5, 2024 14:07:09 +0000", "2024-03-05T14:07:09Z"},
+		{"March 5, 2024", "2024-03-05T00:00:00Z"},
+		{"Tuesday, 05 Sept 2024 14:07:09 +0000", "2024-09-05T14:07:09Z"},
+		{"Tue., 05 Mar 2024 14:07:09 +0000", "2024-03-05T14:07:09Z"},
+		{"  Tue,  05 Mar   2024 14:07:09 +0000 ", "2024-03-05T14:07:09Z"},
+
+		// Named zones and offsets relative to them.
+		{"Tue, 05 Mar 2024 14:07:09 GMT+2", "2024-03-05T12:07:09Z"},
+		{"Tue, 05 Mar 2024 14:07:09 UTC-05:30", "2024-03-05T19:37:09Z"},
+		{"Tue, 05 Mar 2024 14:07:09 utc+0100", "2024-03-05T13:07:09Z"},
+
+		// Unknown zone names are read as UTC rather than rejected.
+		{"Tue, 05 Mar 2024 14:07:09 IST", "2024-03-05T14:07:09Z"},
+		{"Tue, 05 Mar 2024 14:07:09 NZDT", "2024-03-05T14:07:09Z"},
+		{"Tue Mar  5 14:07:09 WIB 2024", "2024-03-05T14:07:09Z"},
+		{"05 MAR 2024 14:07:09 +0000", "2024-03-05T14:07:09Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.date, func(t *testing.T) {
+			got, err := parseDate(tt.date)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Location() != time.UTC {
+				t.Errorf("location = %v, want UTC", got.Location())
+			}
+			if s := got.Format(time.RFC3339Nano); s != tt.want {
+				t.Errorf("got %s, want %s", s, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDateZoneOffsets(t *testing.T) {
+	for name, offset := range zoneOffsets {
+		t.Run(name, func(t *testing.T) {
+			got, err := parseDate("Tue, 05 Mar 2024 14:07:09 " + name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := time.Parse("2006-01-02 15:04:05 -0700", "2024-03-05 14:07:09 "+offset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(want) {
+				t.Errorf("got %s, want %s", got, want.UTC())
+			}
+		})
+	}
+}
+
+// TestParseDateLayouts checks that every layout of dateFormats is reached,
+// that is that no earlier layout reads its dates differently.
+func TestParseDateLayouts(t *testing.T) {
+	ref := time.Date(2024, time.March, 5, 14, 7, 9, 0, time.FixedZone("", -4*60*60))
+	for _, format := range dateFormats {
+		t.Run(format, func(t *testing.T) {
+			value := ref.Format(format)
+			want, err := time.Parse(format, value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := parseDate(value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(want) {
+				t.Errorf("parseDate(%q) = %s, want %s", value, got, want.UTC())
+			}
+		})
+	}
+}
+
+func TestParseDateInvalid(t *testing.T) {
+	for _, date := range []string{
+		"",
+		"   ",
+		"yesterday",
+		"2024-13-45",
+		"Tue, 32 Mar 2024 14:07:09 +0000",
+		"05 Foo 2024 14:07:09 +0000",
+		"Tue, 05 Mar 2024 14:07:09 +0000 trailing",
+		"<b>not a date</b>",
+	} {
+		_, err := parseDate(date)
+		if err == nil {
+			t.Errorf("parseDate(%q) succeeded", date)
+		}
+	}
+}
+
+func TestNormalizeDate(t *testing.T) {
+	tests := []struct {
+		name  string
+		dates []string
+		want  string
+	}{
+		{"none", nil, ""},
+		{"empty", []string{""}, ""},
+		{"blank", []string{"  ", "\t"}, ""},
+		{"garbage", []string{"soon", "later"}, ""},
+		{"first", []string{"2024-03-05T14:07:09+02:00", "2020-01-01"}, "2024-03-05T12:07:09Z"},
+		{"skips empty", []string{"", "Tue, 05 Mar 2024 14:07:09 EST"}, "2024-03-05T19:07:09Z"},
+		{"skips garbage", []string{"n/a", "2024-03-05"}, "2024-03-05T00:00:00Z"},
+		{"drops fraction", []string{"2024-03-05T14:07:09.999Z"}, "2024-03-05T14:07:09Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeDate(tt.dates...); got != tt.want {
+				t.Errorf("normalizeDate(%q) = %q, want %q", tt.dates, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,77 +2,282 @@ package syndication
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"regexp"
 	"strings"
+	"sync"
+	"time"
 
 	"golang.org/x/net/html"
 )
 
-func extractFeedLink(n *html.Node) *string {
+// jsonFeedVersionPrefix starts the version of every JSON Feed.
+const jsonFeedVersionPrefix = "https://jsonfeed.org/version/"
+
+// feedLinkTypes maps the link types that advertise a feed to its format.
+var feedLinkTypes = map[string]FeedType{
+	"application/rss+xml":   RSS,
+	"application/atom+xml":  Atom,
+	"application/rdf+xml":   RDF,
+	"application/feed+json": JSONFeed,
+}
+
+// FeedCandidate is a feed advertised by a web page.
+type FeedCandidate struct {
+	URL   string
+	Title string
+	Type  FeedType
+}
+
+// extractFeedLinks returns the feeds advertised by the link elements of a
+// page, in document order. URLs are returned as written.
+func extractFeedLinks(n *html.Node) []FeedCandidate {
+	var candidates []FeedCandidate
 	if n.Type == html.ElementNode && n.Data == "link" {
-		var feedType, feedURL string
+		var linkType, href, title string
 		for _, attr := range n.Attr {
 			switch attr.Key {
 			case "type":
-				feedType = attr.Val
+				linkType = attr.Val
 			case "href":
-				feedURL = attr.Val
+				href = attr.Val
+			case "title":
+				title = attr.Val
 			}
 		}
-		if feedType == "application/rss+xml" || feedType == "application/atom+xml" {
-			return &feedURL
+		mimeType := strings.ToLower(strings.TrimSpace(strings.Split(linkType, ";")[0]))
+		if ft, ok := feedLinkTypes[mimeType]; ok && strings.TrimSpace(href) != "" {
+			candidates = append(candidates, FeedCandidate{
+				URL:   strings.TrimSpace(href),
+				Title: strings.TrimSpace(title),
+				Type:  ft,
+			})
 		}
 	}
 
 	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		if res := extractFeedLink(c); res != nil {
-			return res
+		candidates = append(candidates, extractFeedLinks(c)...)
+	}
+	return candidates
+}
+
+// wellKnownFeedPaths are where feeds are commonly published, tried when a
+// page does not advertise any.
+var wellKnownFeedPaths = []string{"/feed", "/rss", "/rss.xml", "/atom.xml", "/index.xml", "/feed.json"}
+
+// maxFeedAnchors bounds how many of a page's links are tried as feeds.
+const maxFeedAnchors = 5
+
+// maxConcurrentGuesses bounds how many guessed feed URLs are fetched at once.
+const maxConcurrentGuesses = 3
+
+var (
+	feedHrefPattern = regexp.MustCompile(`(?i)(rss|atom|feed)|\.xml$`)
+	feedTextPattern = regexp.MustCompile(`(?i)\b(rss|atom|feeds?)\b`)
+)
+
+// extractFeedAnchors returns the targets of the anchors whose href or text
+// suggests they lead to a feed, in document order.
+func extractFeedAnchors(n *html.Node) []string {
+	var hrefs []string
+	if n.Type == html.ElementNode && n.Data == "a" {
+		for _, attr := range n.Attr {
+			if attr.Key != "href" {
+				continue
+			}
+			href := strings.TrimSpace(attr.Val)
+			if href != "" && (feedHrefPattern.MatchString(href) || feedTextPattern.MatchString(textContent(n))) {
+				hrefs = append(hrefs, href)
+			}
 		}
 	}
-	return nil
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		hrefs = append(hrefs, extractFeedAnchors(c)...)
+	}
+	return hrefs
 }
 
-func discoverFeedURL(rawURL string) (string, error) {
-	resp, err := http.Get(rawURL)
-	if err != nil {
-		return "", err
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
 	}
-	defer resp.Body.Close()
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(textContent(c))
+	}
+	return b.String()
+}
 
-	doc, err := html.Parse(resp.Body)
+// discoverFeeds returns every feed advertised by the page at rawURL, with
+// absolute URLs and without duplicates. When the page advertises none, the
+// anchors that look like feeds and the well-known feed paths of its site are
+// tried instead, keeping those that parse as feeds.
+func (f *Fetcher) discoverFeeds(ctx context.Context, rawURL string) ([]FeedCandidate, error) {
+	resp, err := f.fetch(ctx, http.MethodGet, rawURL, nil)
 	if err != nil {
-		return "", err
-	}
-	feedLink := extractFeedLink(doc)
-	if feedLink == nil {
-		return "", ErrFeedNotFound
+		return nil, err
 	}
 
-	feedURL, err := url.Parse(*feedLink)
+	doc, err := html.Parse(bytes.NewReader(resp.body))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	if !feedURL.IsAbs() {
-		baseURL, err := url.Parse(rawURL)
+	// Resolve against the page we ended up on after any redirects.
+	base := resp.Request.URL
+
+	var candidates []FeedCandidate
+	seen := map[string]bool{}
+	for _, candidate := range extractFeedLinks(doc) {
+		feedURL, err := url.Parse(candidate.URL)
 		if err != nil {
-			return "", err
+			continue
+		}
+		candidate.URL = base.ResolveReference(feedURL).String()
+		if seen[candidate.URL] {
+			continue
 		}
-		feedURL = baseURL.ResolveReference(feedURL)
+		seen[candidate.URL] = true
+		candidates = append(candidates, candidate)
+	}
+	if len(candidates) > 0 {
+		return candidates, nil
+	}
+
+	var guesses []string
+	for _, href := range extractFeedAnchors(doc) {
+		guess, err := base.Parse(href)
+		if err != nil || (guess.Scheme != "http" && guess.Scheme != "https") || seen[guess.String()] {
+			continue
+		}
+		seen[guess.String()] = true
+		guesses = append(guesses, guess.String())
+		if len(guesses) == maxFeedAnchors {
+			break
+		}
+	}
+	for _, path := range wellKnownFeedPaths {
+		guess := base.ResolveReference(&url.URL{Path: path}).String()
+		if !seen[guess] {
+			seen[guess] = true
+			guesses = append(guesses, guess)
+		}
+	}
+
+	candidates = f.validateFeedURLs(ctx, guesses)
+	if len(candidates) == 0 {
+		return nil, ErrFeedNotFound
 	}
-	return feedURL.String(), nil
+	return candidates, nil
 }
 
-func isFeedURL(url string) (bool, error) {
-	resp, err := http.Head(url)
+// validateFeedURLs fetches the given URLs, maxConcurrentGuesses at a time, and
+// returns those that parse as feeds, in the order given. URLs serving a feed
+// already returned under another address, judged by its self link or its
+// title, site and latest entry, are dropped.
+func (f *Fetcher) validateFeedURLs(ctx context.Context, urls []string) []FeedCandidate {
+	feeds := make([]*Feed, len(urls))
+	sem := make(chan struct{}, maxConcurrentGuesses)
+	var wg sync.WaitGroup
+	for i, feedURL := range urls {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			resp, err := f.fetch(ctx, http.MethodGet, feedURL, nil)
+			if err != nil || resp.StatusCode != http.StatusOK {
+				return
+			}
+			feed, err := parseFeed(resp.body, feedURL, contentTypeCharset(resp.Header.Get("Content-Type")))
+			if err != nil {
+				return
+			}
+			feeds[i] = feed
+		}()
+	}
+	wg.Wait()
+
+	var candidates []FeedCandidate
+	seen := map[string]bool{}
+	for i, feed := range feeds {
+		if feed == nil {
+			continue
+		}
+		identity := feed.Title + "\x00" + feed.SiteURL
+		if len(feed.Entries) > 0 {
+			identity += "\x00" + feed.Entries[0].GUID()
+		}
+		if seen[feed.FeedURL] || seen[identity] {
+			continue
+		}
+		seen[feed.FeedURL] = true
+		seen[identity] = true
+		candidates = append(candidates, FeedCandidate{
+			URL:   urls[i],
+			Title: feed.Title,
+			Type:  feed.Type,
+		})
+	}
+	return candidates
+}
+
+// discoveryCache remembers the outcome of recent discoveries by URL.
+type discoveryCache struct {
+	mu      sync.Mutex
+	entries map[string]discovery
+}
+
+type discovery struct {
+	candidates []FeedCandidate
+	err        error
+	expires    time.Time
+}
+
+func (dc *discoveryCache) get(url string) (discovery, bool) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	d, ok := dc.entries[url]
+	if !ok || time.Now().After(d.expires) {
+		return discovery{}, false
+	}
+	return d, true
+}
+
+func (dc *discoveryCache) put(url string, candidates []FeedCandidate, err error, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	now := time.Now()
+	if dc.entries == nil {
+		dc.entries = map[string]discovery{}
+	}
+	for key, d := range dc.entries {
+		if now.After(d.expires) {
+			delete(dc.entries, key)
+		}
+	}
+	dc.entries[url] = discovery{
+		candidates: candidates,
+		err:        err,
+		expires:    now.Add(ttl),
+	}
+}
+
+func (f *Fetcher) isFeedURL(ctx context.Context, url string) (bool, error) {
+	resp, err := f.fetch(ctx, http.MethodHead, url, nil)
 	if err != nil {
 		return false, err
 	}
-	defer resp.Body.Close()
 
 	contentTypeRaw := resp.Header["Content-Type"]
 	if len(contentTypeRaw) == 0 {
@@ -81,23 +286,54 @@ func isFeedURL(url string) (bool, error) {
 
 	contentType := strings.Split(contentTypeRaw[0], ";")[0]
 	feedMimeTypes := map[string]bool{
-		"text/xml":             true,
-		"application/xml":      true,
-		"application/rss+xml":  true,
-		"application/atom+xml": true,
+		"text/xml":              true,
+		"application/xml":       true,
+		"application/rss+xml":   true,
+		"application/atom+xml":  true,
+		"application/rdf+xml":   true,
+		"application/feed+json": true,
 	}
 
-	if contentType == "text/html" {
+	switch {
+	case contentType == "text/html":
 		return false, nil
-	}
-	if feedMimeTypes[contentType] {
+	case contentType == "application/json":
+		return f.isJSONFeedURL(ctx, url)
+	case feedMimeTypes[contentType]:
 		return true, nil
 	}
 	return false, fmt.Errorf("Invalid content type: %s", contentType)
 }
 
-func detectFeedType(body []byte) (string, error) {
-	decoder := xml.NewDecoder(bytes.NewReader(body))
+// isJSONFeedURL reports whether the JSON document at url is a JSON Feed
+// rather than the response of some other API. A JSON Feed declares its
+// version as a jsonfeed.org URL.
+func (f *Fetcher) isJSONFeedURL(ctx context.Context, url string) (bool, error) {
+	resp, err := f.fetch(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("Unexpected status code: %d", resp.StatusCode)
+	}
+	var doc struct {
+		Version string `json:"version"`
+	}
+	err = json.Unmarshal(resp.body, &doc)
+	if err != nil || !strings.HasPrefix(doc.Version, jsonFeedVersionPrefix) {
+		return false, ErrFeedNotFound
+	}
+	return true, nil
+}
+
+// isJSONFeed reports whether the body looks like a JSON document rather than XML.
+func isJSONFeed(body []byte) bool {
+	trimmed := bytes.TrimLeft(body, " \t\r\n\ufeff")
+	return len(trimmed) > 0 && trimmed[0] == '{'
+}
+
+func detectFeedType(body []byte, charset string) (string, error) {
+	decoder := newXMLDecoder(body, charset)
 
 	for {
 		token, err := decoder.Token()
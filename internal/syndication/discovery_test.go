@@ -0,0 +1,88 @@
+package syndication
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestValidateFeedURLs(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}()
+		time.Sleep(10 * time.Millisecond)
+
+		if !strings.HasPrefix(r.URL.Path, "/feed") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprintf(w, `<rss version="2.0"><channel><title>%s</title></channel></rss>`, r.URL.Path)
+	}))
+	defer srv.Close()
+
+	var urls []string
+	for i := range 10 {
+		urls = append(urls, fmt.Sprintf("%s/missing%d", srv.URL, i))
+	}
+	urls = append(urls, srv.URL+"/feed2", srv.URL+"/feed1")
+
+	f := NewFetcher(nil, "", 0, 0)
+	candidates := f.validateFeedURLs(t.Context(), urls)
+	if maxInFlight > maxConcurrentGuesses {
+		t.Errorf("%d requests in flight, want at most %d", maxInFlight, maxConcurrentGuesses)
+	}
+	if len(candidates) != 2 || candidates[0].URL != srv.URL+"/feed2" || candidates[1].URL != srv.URL+"/feed1" {
+		t.Errorf("got candidates %+v, want feed2 and feed1 in order", candidates)
+	}
+}
+
+func TestDiscoverFeedsJSON(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/feed.json":
+			w.Header().Set("Content-Type", "application/feed+json")
+		default:
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		}
+		switch r.URL.Path {
+		case "/api/users":
+			fmt.Fprint(w, `{"users": [{"id": 1}], "version": "2"}`)
+		case "/api/list":
+			fmt.Fprint(w, `[1, 2, 3]`)
+		default:
+			fmt.Fprint(w, `{"version": "https://jsonfeed.org/version/1.1", "title": "Blog", "items": []}`)
+		}
+	}))
+	defer srv.Close()
+
+	tests := map[string]bool{
+		"/feed.json": true,
+		"/blog.json": true,
+		"/api/users": false,
+		"/api/list":  false,
+	}
+	for path, isFeed := range tests {
+		f := NewFetcher(nil, "", 0, 0)
+		candidates, err := f.DiscoverFeeds(t.Context(), srv.URL+path)
+		if isFeed && (err != nil || len(candidates) != 1 || candidates[0].URL != srv.URL+path) {
+			t.Errorf("%s: got %v, %v; want the URL itself", path, candidates, err)
+		} else if !isFeed && !errors.Is(err, ErrFeedNotFound) {
+			t.Errorf("%s: got %v, %v; want ErrFeedNotFound", path, candidates, err)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package syndication
+
+import (
+	"strconv"
+	"strings"
+)
+
+// iTunesModule holds the per-item elements of Apple's podcast namespace.
+type iTunesModule struct {
+	Duration string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+	Episode  string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd episode"`
+	Image    struct {
+		Href string `xml:"href,attr"`
+	} `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+}
+
+// apply copies the item level podcast details onto each of its enclosures,
+// keeping any value an enclosure already carries.
+func (im iTunesModule) apply(enclosures []Enclosure) {
+	duration := parseDuration(im.Duration)
+	episode := strings.TrimSpace(im.Episode)
+	image := strings.TrimSpace(im.Image.Href)
+	for i := range enclosures {
+		if enclosures[i].Duration == 0 {
+			enclosures[i].Duration = duration
+		}
+		if enclosures[i].Episode == "" {
+			enclosures[i].Episode = episode
+		}
+		if enclosures[i].ImageURL == "" {
+			enclosures[i].ImageURL = image
+		}
+	}
+}
+
+type RSSEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length string `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+func (re RSSEnclosure) toEnclosure() Enclosure {
+	return Enclosure{
+		URL:    strings.TrimSpace(re.URL),
+		Type:   strings.TrimSpace(re.Type),
+		Length: parseLength(re.Length),
+	}
+}
+
+// parseDuration accepts the forms used by itunes:duration: a number of
+// seconds, MM:SS or HH:MM:SS. It returns the duration in seconds.
+func parseDuration(duration string) int64 {
+	duration = strings.TrimSpace(duration)
+	if duration == "" {
+		return 0
+	}
+
+	var seconds int64
+	for _, part := range strings.Split(duration, ":") {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil || n < 0 {
+			return 0
+		}
+		seconds = seconds*60 + int64(n)
+	}
+	return seconds
+}
+
+func parseLength(length string) int64 {
+	n, err := strconv.ParseInt(strings.TrimSpace(length), 10, 64)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
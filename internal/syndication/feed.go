@@ -1,16 +1,32 @@
 package syndication
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
 type FeedType string
 
 const (
-	RSS  FeedType = "rss"
-	Atom FeedType = "atom"
+	RSS      FeedType = "rss"
+	Atom     FeedType = "atom"
+	JSONFeed FeedType = "json"
+	RDF      FeedType = "rdf"
+	// Scraper feeds are built from an HTML page with ScraperRules.
+	Scraper FeedType = "scraper"
 )
 
 type FeedConvertible interface {
 	toFeed() *Feed
 }
+
+// FeedEntry is an entry in any of the supported formats. Description is the
+// entry's summary and Content its full body, both as HTML. Published and
+// Updated are UTC RFC 3339 dates, and Published is empty when the entry
+// carries no usable date at all. Categories are the topics the publisher
+// filed the entry under, or the feed's when the entry has none of its own.
 type FeedEntry struct {
+	ID          string
 	Title       string
 	Description string
 	Published   string
@@ -18,6 +34,38 @@ type FeedEntry struct {
 	Author      string
 	Link        string
 	Content     string
+	Categories  []string
+	Enclosures  []Enclosure
+	Media       Media
+
+	// base is the entry's xml:base, if any.
+	base string
+}
+
+// GUID returns a stable identifier for the entry within its feed. Entries
+// without an explicit id fall back to their link, then to a hash of their
+// title and content.
+func (fe FeedEntry) GUID() string {
+	if fe.ID != "" {
+		return fe.ID
+	}
+	if fe.Link != "" {
+		return fe.Link
+	}
+	sum := sha256.Sum256([]byte(fe.Title + "\x00" + fe.Content))
+	return hex.EncodeToString(sum[:])
+}
+
+// Enclosure is a media file attached to an entry, such as a podcast episode.
+// Length is in bytes and Duration in seconds.
+type Enclosure struct {
+	URL      string
+	Type     string
+	Title    string
+	Length   int64
+	Duration int64
+	Episode  string
+	ImageURL string
 }
 
 type Feed struct {
@@ -26,11 +74,36 @@ type Feed struct {
 	Title    string
 	FeedURL  string
 	SiteURL  string
+	// IconURL is the image the feed advertises for itself, if any.
+	IconURL string
+	// HubURL is the WebSub hub the feed is published through, if any, and
+	// TopicURL the address that identifies the feed to the hub.
+	HubURL   string
+	TopicURL string
 	Entries  []FeedEntry
+	// MovedTo is the feed's new address when it was permanently redirected.
+	MovedTo string
+	CacheValidators
+	UpdateHints
+
+	// base is the feed's xml:base, if any.
+	base string
+	// categories are the feed's own categories, which its entries without
+	// any inherit.
+	categories []string
+}
+
+// CacheValidators hold the HTTP validators a server returned for a feed so
+// that subsequent requests can be made conditional.
+type CacheValidators struct {
+	ETag         string
+	LastModified string
 }
 
 type Link struct {
-	Href string `xml:"href,attr"`
-	Rel  string `xml:"rel,attr,omitempty"`
-	Type string `xml:"type,attr"`
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr,omitempty"`
+	Type   string `xml:"type,attr"`
+	Title  string `xml:"title,attr"`
+	Length string `xml:"length,attr"`
 }
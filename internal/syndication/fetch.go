@@ -1,53 +1,202 @@
 package syndication
 
 import (
-	"encoding/xml"
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"time"
 )
 
-func GetNewEntries(feedURL string, ft FeedType, cutoff string) ([]FeedEntry, error) {
-	resp, err := http.Get(feedURL)
+const DefaultUserAgent = "sammler/1.0 (+https://github.com/oahshtsua/sammler)"
+
+const DefaultMaxRedirects = 10
+
+const DefaultDiscoveryCacheTTL = 5 * time.Minute
+
+var ErrResponseTooLarge = errors.New("Response body exceeds the size limit")
+var ErrTooManyRedirects = errors.New("Too many redirects")
+
+// Fetcher performs every network request made by the package. It bounds each
+// request by Timeout, refuses to read more than MaxBodySize bytes and follows
+// at most MaxRedirects redirects. Discovery results are reused for
+// DiscoveryCacheTTL.
+type Fetcher struct {
+	Client            *http.Client
+	UserAgent         string
+	Timeout           time.Duration
+	MaxBodySize       int64
+	MaxRedirects      int
+	DiscoveryCacheTTL time.Duration
+
+	discoveries discoveryCache
+	// options are set on fetchers returned by WithOptions. Their headers and
+	// credentials are only sent to optionsHost.
+	options     RequestOptions
+	optionsHost string
+}
+
+// response is an HTTP response whose body has been read in full.
+type response struct {
+	*http.Response
+	body []byte
+	// movedTo is where the leading run of permanent (301/308) redirects
+	// ended, or empty when the first hop was not a permanent redirect.
+	movedTo string
+}
+
+func NewFetcher(client *http.Client, userAgent string, timeout time.Duration, maxBodySize int64) *Fetcher {
+	if client == nil {
+		client = &http.Client{}
+	}
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+	return &Fetcher{
+		Client:            client,
+		UserAgent:         userAgent,
+		Timeout:           timeout,
+		MaxBodySize:       maxBodySize,
+		MaxRedirects:      DefaultMaxRedirects,
+		DiscoveryCacheTTL: DefaultDiscoveryCacheTTL,
+	}
+}
+
+// fetch performs the request and reads the whole body before returning, so
+// the per-request deadline covers the transfer as well as the headers.
+func (f *Fetcher) fetch(ctx context.Context, method, rawURL string, header http.Header) (*response, error) {
+	return f.send(ctx, method, rawURL, header, nil)
+}
+
+// send is fetch with a request body.
+func (f *Fetcher) send(ctx context.Context, method, rawURL string, header http.Header, body io.Reader) (*response, error) {
+	if f.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, body)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	decoder := xml.NewDecoder(resp.Body)
-	var newEntries []FeedEntry
-	switch ft {
-	case RSS:
-		updatedFeed := RSSFeed{}
-		err := decoder.Decode(&updatedFeed)
-		if err != nil {
-			return nil, err
-		}
+	f.applyOptions(req)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("User-Agent", f.UserAgent)
 
-		for _, entry := range updatedFeed.Channel.Items {
-			fe, err := entry.toFeedEntry()
-			if err != nil {
-				continue
-			}
-			if fe.Published < cutoff {
-				break
-			}
-			newEntries = append(newEntries, *fe)
+	var movedTo string
+	permanent := true
+	client := *f.Client
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) > f.MaxRedirects {
+			return ErrTooManyRedirects
 		}
-	case Atom:
-		updatedFeed := AtomFeed{}
-		err := decoder.Decode(&updatedFeed)
-		if err != nil {
-			return nil, err
-		}
-		for _, entry := range updatedFeed.Entries {
-			fe := entry.toFeedEntry()
-			if fe.Published < cutoff {
-				break
+		// The client keeps credentials and cookies on redirects to another
+		// port or a subdomain, and custom headers everywhere.
+		f.removeOptions(req)
+		switch req.Response.StatusCode {
+		case http.StatusMovedPermanently, http.StatusPermanentRedirect:
+			if permanent {
+				movedTo = req.URL.String()
 			}
-			newEntries = append(newEntries, *fe)
+		default:
+			permanent = false
 		}
+		return nil
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var reader io.Reader = resp.Body
+	if f.MaxBodySize > 0 {
+		reader = io.LimitReader(resp.Body, f.MaxBodySize+1)
+	}
+	respBody, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if f.MaxBodySize > 0 && int64(len(respBody)) > f.MaxBodySize {
+		return nil, ErrResponseTooLarge
+	}
+	return &response{Response: resp, body: respBody, movedTo: movedTo}, nil
+}
+
+// GetNewEntries fetches the feed at feedURL. The returned feed carries the
+// validators to send on the next request, and MovedTo is set when the feed
+// was permanently redirected. When the server reports that nothing changed
+// since cv was issued, a *NotModifiedError is returned.
+func (f *Fetcher) GetNewEntries(ctx context.Context, feedURL string, ft FeedType, cv CacheValidators) (*Feed, error) {
+	resp, err := f.fetchDocument(ctx, feedURL, cv)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.movedTo != "" {
+		feedURL = resp.movedTo
+	}
+	feed, err := decodeFeed(resp.body, ft, feedURL, contentTypeCharset(resp.Header.Get("Content-Type")))
+	if err != nil {
+		return nil, err
+	}
+	feed.CacheValidators = cacheValidatorsFromResponse(resp)
+	feed.MovedTo = resp.movedTo
+	applyLinkHeaders(feed, resp)
+	return feed, nil
+}
+
+// fetchDocument makes a request conditional on cv and turns the statuses
+// that do not carry a document into errors.
+func (f *Fetcher) fetchDocument(ctx context.Context, rawURL string, cv CacheValidators) (*response, error) {
+	header := http.Header{}
+	if cv.ETag != "" {
+		header.Set("If-None-Match", cv.ETag)
+	}
+	if cv.LastModified != "" {
+		header.Set("If-Modified-Since", cv.LastModified)
+	}
+
+	resp, err := f.fetch(ctx, http.MethodGet, rawURL, header)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp, nil
+	case http.StatusNotModified:
+		return nil, &NotModifiedError{MovedTo: resp.movedTo}
+	case http.StatusGone:
+		return nil, ErrFeedGone
 	default:
-		return nil, ErrFeedNotSupported
+		return nil, fmt.Errorf("Unexpected status code: %d", resp.StatusCode)
+	}
+}
+
+// NotModifiedError is returned when the server reports that a document has
+// not changed. MovedTo is set, as on Feed, when the request was permanently
+// redirected on the way.
+type NotModifiedError struct {
+	MovedTo string
+}
+
+func (e *NotModifiedError) Error() string {
+	return ErrNotModified.Error()
+}
+
+func (e *NotModifiedError) Unwrap() error {
+	return ErrNotModified
+}
 
+func cacheValidatorsFromResponse(resp *response) CacheValidators {
+	return CacheValidators{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
 	}
-	return newEntries, nil
 }
@@ -0,0 +1,176 @@
+package syndication
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// redirectServer redirects /n to /n-1 until /0, which answers with a feed.
+func redirectServer(t *testing.T, status int) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, err := strconv.Atoi(r.URL.Path[1:])
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if n > 0 {
+			http.Redirect(w, r, "/"+strconv.Itoa(n-1), status)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`<rss version="2.0"><channel><title>Blog</title></channel></rss>`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFetcherMaxRedirects(t *testing.T) {
+	srv := redirectServer(t, http.StatusFound)
+
+	tests := []struct {
+		maxRedirects int
+		redirects    int
+		wantErr      bool
+	}{
+		{maxRedirects: 0, redirects: 0},
+		{maxRedirects: 0, redirects: 1, wantErr: true},
+		{maxRedirects: 3, redirects: 3},
+		{maxRedirects: 3, redirects: 4, wantErr: true},
+		{maxRedirects: DefaultMaxRedirects, redirects: DefaultMaxRedirects},
+		{maxRedirects: DefaultMaxRedirects, redirects: DefaultMaxRedirects + 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		f := NewFetcher(nil, "", 0, 0)
+		f.MaxRedirects = tt.maxRedirects
+		_, err := f.GetNewEntries(t.Context(), srv.URL+"/"+strconv.Itoa(tt.redirects), RSS, CacheValidators{})
+		if tt.wantErr != errors.Is(err, ErrTooManyRedirects) {
+			t.Errorf("MaxRedirects %d, %d redirects: got %v", tt.maxRedirects, tt.redirects, err)
+		}
+	}
+}
+
+func TestFetcherMovedTo(t *testing.T) {
+	srv := redirectServer(t, http.StatusMovedPermanently)
+	f := NewFetcher(nil, "", 0, 0)
+
+	feed, err := f.GetNewEntries(t.Context(), srv.URL+"/2", RSS, CacheValidators{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := srv.URL + "/0"; feed.MovedTo != want {
+		t.Errorf("MovedTo = %q, want %q", feed.MovedTo, want)
+	}
+
+	// A feed that moved and did not change reports both.
+	_, err = f.GetNewEntries(t.Context(), srv.URL+"/2", RSS, feed.CacheValidators)
+	var notModified *NotModifiedError
+	if !errors.As(err, &notModified) || !errors.Is(err, ErrNotModified) {
+		t.Fatalf("got %v, want a NotModifiedError", err)
+	}
+	if want := srv.URL + "/0"; notModified.MovedTo != want {
+		t.Errorf("MovedTo = %q, want %q", notModified.MovedTo, want)
+	}
+
+	_, err = f.GetNewEntries(t.Context(), srv.URL+"/0", RSS, feed.CacheValidators)
+	if !errors.As(err, &notModified) || notModified.MovedTo != "" {
+		t.Errorf("got %v with MovedTo %q, want no move", err, notModified.MovedTo)
+	}
+}
+
+func TestNewFetcherUserAgent(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("User-Agent")
+	}))
+	defer srv.Close()
+
+	for userAgent, want := range map[string]string{
+		"":                   DefaultUserAgent,
+		"my-reader/2.0 (+x)": "my-reader/2.0 (+x)",
+	} {
+		f := NewFetcher(nil, userAgent, 0, 0)
+		_, err := f.fetch(t.Context(), http.MethodGet, srv.URL, http.Header{"User-Agent": {"overridden"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("user agent %q: sent %q, want %q", userAgent, got, want)
+		}
+	}
+}
+
+func TestNewFetcherTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow-body" {
+			w.Write([]byte("<rss>"))
+			w.(http.Flusher).Flush()
+		}
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	f := NewFetcher(nil, "", 50*time.Millisecond, 0)
+	for _, path := range []string{"/slow-headers", "/slow-body"} {
+		start := time.Now()
+		_, err := f.fetch(t.Context(), http.MethodGet, srv.URL+path, nil)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%s: got %v, want a deadline error", path, err)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("%s: took %v", path, elapsed)
+		}
+	}
+}
+
+func TestNewFetcherMaxBodySize(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(r.URL.Query().Get("n"))
+		w.Write([]byte(strings.Repeat("x", n)))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		maxBodySize int64
+		size        int
+		wantErr     bool
+	}{
+		{maxBodySize: 1024, size: 1023},
+		{maxBodySize: 1024, size: 1024},
+		{maxBodySize: 1024, size: 1025, wantErr: true},
+		{maxBodySize: 1024, size: 1 << 20, wantErr: true},
+		{maxBodySize: 0, size: 1 << 20},
+	}
+	for _, tt := range tests {
+		f := NewFetcher(nil, "", 0, tt.maxBodySize)
+		resp, err := f.fetch(t.Context(), http.MethodGet, srv.URL+"/?n="+strconv.Itoa(tt.size), nil)
+		if tt.wantErr {
+			if !errors.Is(err, ErrResponseTooLarge) {
+				t.Errorf("limit %d, size %d: got %v, want ErrResponseTooLarge", tt.maxBodySize, tt.size, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("limit %d, size %d: %v", tt.maxBodySize, tt.size, err)
+			continue
+		}
+		if len(resp.body) != tt.size {
+			t.Errorf("limit %d, size %d: read %d bytes", tt.maxBodySize, tt.size, len(resp.body))
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package syndication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var ErrAddressNotAllowed = errors.New("Address not allowed")
+
+// nonPublicNetworks are the special-purpose ranges not covered by the
+// netip.Addr predicates that AddressGuard checks.
+var nonPublicNetworks = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+}
+
+var (
+	// nat64Network is the well-known NAT64 prefix, whose addresses carry the
+	// IPv4 address they are translated to in their last 32 bits.
+	nat64Network = netip.MustParsePrefix("64:ff9b::/96")
+	// sixToFourNetwork is the 6to4 prefix, whose addresses carry the IPv4
+	// address of the relay in the 32 bits after it.
+	sixToFourNetwork = netip.MustParsePrefix("2002::/16")
+)
+
+// AddressError is returned when a connection is refused because the host
+// resolved to an address that is not publicly routable.
+type AddressError struct {
+	Host string
+	Addr netip.Addr
+}
+
+func (e *AddressError) Error() string {
+	if e.Host == "" || e.Host == e.Addr.String() {
+		return fmt.Sprintf("%s is not a public address", e.Addr)
+	}
+	return fmt.Sprintf("%s resolves to %s, which is not a public address", e.Host, e.Addr)
+}
+
+func (e *AddressError) Unwrap() error {
+	return ErrAddressNotAllowed
+}
+
+// AddressGuard keeps requests from reaching loopback, private, link-local and
+// other non-public addresses, so that user-supplied URLs cannot be used to
+// probe the server's own network. The check is made on the address each
+// connection is actually made to, after name resolution, which covers
+// redirects and hosts whose records change between lookups. Networks and
+// hosts on the allowlist are let through.
+type AddressGuard struct {
+	networks []netip.Prefix
+	hosts    []string
+}
+
+// NewAddressGuard returns a guard with the given allowlist. Entries are IP
+// addresses, CIDR networks, host names, or host name suffixes written as
+// "*.example.com". Empty entries are ignored.
+func NewAddressGuard(allowlist []string) (*AddressGuard, error) {
+	g := &AddressGuard{}
+	for _, entry := range allowlist {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			g.networks = append(g.networks, prefix.Masked())
+			continue
+		}
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			g.networks = append(g.networks, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		if strings.ContainsAny(entry, "/:") {
+			return nil, fmt.Errorf("Invalid allowlist entry %q", entry)
+		}
+		g.hosts = append(g.hosts, entry)
+	}
+	return g, nil
+}
+
+// Transport returns a clone of http.DefaultTransport whose connections go
+// through the guard. Proxies set in the environment are not used, as the
+// guard would only see the proxy's address and not the one requested.
+func (g *AddressGuard) Transport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = g.DialContext(&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	})
+	return transport
+}
+
+// DialContext wraps dialer so that connections to addresses the guard does
+// not allow fail with an *AddressError.
+func (g *AddressGuard) DialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		if g.allowsHost(host) {
+			return dialer.DialContext(ctx, network, addr)
+		}
+
+		d := *dialer
+		d.Control = func(network, address string, c syscall.RawConn) error {
+			if dialer.Control != nil {
+				if err := dialer.Control(network, address, c); err != nil {
+					return err
+				}
+			}
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !g.allowsAddr(addrPort.Addr()) {
+				return &AddressError{Addr: addrPort.Addr().Unmap()}
+			}
+			return nil
+		}
+
+		conn, err := d.DialContext(ctx, network, addr)
+		var addrErr *AddressError
+		if errors.As(err, &addrErr) {
+			addrErr.Host = host
+		}
+		return conn, err
+	}
+}
+
+func (g *AddressGuard) allowsHost(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, allowed := range g.hosts {
+		if suffix, ok := strings.CutPrefix(allowed, "*"); ok {
+			if strings.HasSuffix(host, suffix) {
+				return true
+			}
+		} else if host == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *AddressGuard) allowsAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	v4, embedded := embeddedIPv4(addr)
+	for _, network := range g.networks {
+		if network.Contains(addr) || embedded && network.Contains(v4) {
+			return true
+		}
+	}
+	return isPublicAddr(addr)
+}
+
+// isPublicAddr reports whether addr is a globally routable unicast address.
+// NAT64 and 6to4 addresses are public when the IPv4 address they lead to is.
+func isPublicAddr(addr netip.Addr) bool {
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() {
+		return false
+	}
+	for _, network := range nonPublicNetworks {
+		if network.Contains(addr) {
+			return false
+		}
+	}
+	if v4, ok := embeddedIPv4(addr); ok {
+		return isPublicAddr(v4)
+	}
+	return true
+}
+
+// embeddedIPv4 returns the IPv4 address embedded in a NAT64 or 6to4 address.
+func embeddedIPv4(addr netip.Addr) (netip.Addr, bool) {
+	if !addr.Is6() {
+		return netip.Addr{}, false
+	}
+	b := addr.As16()
+	switch {
+	case nat64Network.Contains(addr):
+		return netip.AddrFrom4([4]byte(b[12:16])), true
+	case sixToFourNetwork.Contains(addr):
+		return netip.AddrFrom4([4]byte(b[2:6])), true
+	}
+	return netip.Addr{}, false
+}
@@ -0,0 +1,190 @@
+package syndication
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestAddressGuardDialContext(t *testing.T) {
+	// Addresses that are not public, none of which is dialled.
+	addrs := []string{
+		"127.0.0.1:80",
+		"[::1]:80",
+		"10.0.0.1:80",
+		"172.16.5.4:80",
+		"192.168.1.1:80",
+		"169.254.169.254:80",
+		"100.64.0.1:80",
+		"0.0.0.0:80",
+		"[fc00::1]:80",
+		"[fe80::1]:80",
+		"[::ffff:127.0.0.1]:80",
+		"[::ffff:169.254.169.254]:80",
+		"[64:ff9b::a9fe:a9fe]:80",
+		"[64:ff9b::7f00:1]:80",
+		"[64:ff9b:1::1]:80",
+		"[2002:7f00:1::1]:80",
+		"[2002:c0a8:101::1]:80",
+	}
+
+	guard, err := NewAddressGuard(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dial := guard.DialContext(&net.Dialer{})
+	for _, addr := range addrs {
+		conn, err := dial(t.Context(), "tcp", addr)
+		if conn != nil {
+			conn.Close()
+		}
+		if errors.Is(err, syscall.EAFNOSUPPORT) {
+			t.Logf("%s: %v", addr, err)
+			continue
+		}
+		var addrErr *AddressError
+		if !errors.As(err, &addrErr) || !errors.Is(err, ErrAddressNotAllowed) {
+			t.Errorf("%s: got %v, want an *AddressError", addr, err)
+		}
+	}
+}
+
+func TestIsPublicAddr(t *testing.T) {
+	tests := map[string]bool{
+		"93.184.216.34":        true,
+		"2606:4700::1111":      true,
+		"64:ff9b::5db8:d822":   true,
+		"2002:5db8:d822::1":    true,
+		"::ffff:93.184.216.34": true,
+		"127.0.0.1":            false,
+		"10.1.2.3":             false,
+		"169.254.169.254":      false,
+		"64:ff9b::a9fe:a9fe":   false,
+		"64:ff9b::a00:1":       false,
+		"2002:a00:1::1":        false,
+		"64:ff9b:1::5db8:d822": false,
+		"ff02::1":              false,
+	}
+	for s, want := range tests {
+		addr := netip.MustParseAddr(s)
+		if got := isPublicAddr(addr.Unmap()); got != want {
+			t.Errorf("isPublicAddr(%s) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestAddressGuardAllowlist(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+	port := srv.URL[strings.LastIndex(srv.URL, ":"):]
+
+	tests := []struct {
+		allowlist string
+		host      string
+		allowed   bool
+	}{
+		{"", "127.0.0.1", false},
+		{"", "localhost", false},
+		{"127.0.0.1", "127.0.0.1", true},
+		{"127.0.0.1", "localhost", true},
+		{"::1", "127.0.0.1", false},
+		{"127.0.0.0/8", "127.0.0.1", true},
+		{"10.0.0.0/8", "127.0.0.1", false},
+		{"localhost", "localhost", true},
+		{"LOCALHOST", "localhost", true},
+		{"localhost", "127.0.0.1", false},
+		{"*.example.com", "localhost", false},
+		{"*host", "localhost", true},
+	}
+	for _, tt := range tests {
+		guard, err := NewAddressGuard(strings.Split(tt.allowlist, ","))
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := &http.Client{Transport: guard.Transport()}
+		resp, err := client.Get("http://" + tt.host + port + "/")
+		if err == nil {
+			resp.Body.Close()
+		}
+		if tt.allowed && err != nil {
+			t.Errorf("allowlist %q, host %s: %v", tt.allowlist, tt.host, err)
+		} else if !tt.allowed && !errors.Is(err, ErrAddressNotAllowed) {
+			t.Errorf("allowlist %q, host %s: got %v, want ErrAddressNotAllowed", tt.allowlist, tt.host, err)
+		}
+	}
+}
+
+func TestAddressGuardAllowsHost(t *testing.T) {
+	guard, err := NewAddressGuard([]string{"feeds.internal", "*.corp.example"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]bool{
+		"feeds.internal":      true,
+		"Feeds.Internal.":     true,
+		"news.feeds.internal": false,
+		"wiki.corp.example":   true,
+		"a.b.corp.example":    true,
+		"corp.example":        false,
+		"evilcorp.example":    false,
+	}
+	for host, want := range tests {
+		if got := guard.allowsHost(host); got != want {
+			t.Errorf("allowsHost(%q) = %v, want %v", host, got, want)
+		}
+	}
+
+	for _, entry := range []string{"http://feeds.internal", "10.0.0.0/33", "feeds.internal:8080"} {
+		_, err := NewAddressGuard([]string{entry})
+		if err == nil {
+			t.Errorf("NewAddressGuard(%q) succeeded, want an error", entry)
+		}
+	}
+}
+
+func TestAddressGuardRedirect(t *testing.T) {
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("secret"))
+	}))
+	defer internal.Close()
+	// The allowed server is reached through "localhost" and redirects to the
+	// loopback address, which is not allowed.
+	allowed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, internal.URL+"/", http.StatusFound)
+	}))
+	defer allowed.Close()
+
+	guard, err := NewAddressGuard([]string{"localhost"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: guard.Transport()}
+	resp, err := client.Get(strings.Replace(allowed.URL, "127.0.0.1", "localhost", 1))
+	if err == nil {
+		resp.Body.Close()
+	}
+	var addrErr *AddressError
+	if !errors.As(err, &addrErr) || !errors.Is(err, ErrAddressNotAllowed) {
+		t.Fatalf("got %v, want an *AddressError", err)
+	}
+	if addrErr.Addr.String() != "127.0.0.1" {
+		t.Errorf("refused %s, want 127.0.0.1", addrErr.Addr)
+	}
+}
+
+func TestAddressGuardTransportIgnoresEnvironmentProxy(t *testing.T) {
+	guard, err := NewAddressGuard(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if guard.Transport().Proxy != nil {
+		t.Error("the guarded transport uses the proxy from the environment")
+	}
+}
@@ -0,0 +1,370 @@
+package syndication
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// IconSize is the width and height in pixels of the icons returned by
+// FetchIcon.
+const IconSize = 32
+
+// maxIconDimension is the largest width or height of the images accepted as
+// icons, so that a small file cannot make the decoder allocate a huge image.
+const maxIconDimension = 1024
+
+var ErrIconNotFound = errors.New("No icon found")
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// FetchIcon returns the icon of a feed as an IconSize square PNG. iconURL is
+// the image the feed advertises, if any. When it is missing or unusable, the
+// icons declared by the page at siteURL and then its /favicon.ico are tried.
+func (f *Fetcher) FetchIcon(ctx context.Context, iconURL, siteURL string) ([]byte, error) {
+	tried := map[string]bool{}
+	try := func(candidates ...string) []byte {
+		for _, candidate := range candidates {
+			if candidate == "" || tried[candidate] {
+				continue
+			}
+			tried[candidate] = true
+			icon, err := f.fetchIcon(ctx, candidate)
+			if err == nil {
+				return icon
+			}
+		}
+		return nil
+	}
+
+	if icon := try(iconURL); icon != nil {
+		return icon, nil
+	}
+	if siteURL != "" {
+		if icon := try(f.pageIcons(ctx, siteURL)...); icon != nil {
+			return icon, nil
+		}
+		if icon := try(resolveURL(siteURL, "/favicon.ico")); icon != nil {
+			return icon, nil
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return nil, ErrIconNotFound
+}
+
+func (f *Fetcher) fetchIcon(ctx context.Context, iconURL string) ([]byte, error) {
+	resp, err := f.fetch(ctx, http.MethodGet, iconURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unexpected status code: %d", resp.StatusCode)
+	}
+	return normalizeIcon(resp.body)
+}
+
+// pageIcon is an icon declared by a link element. size is its declared
+// width, or 0 when unknown.
+type pageIcon struct {
+	url  string
+	size int
+}
+
+// pageIcons returns the absolute URLs of the icons declared by the page at
+// pageURL, best suited to IconSize first. Vector icons are left out as they
+// cannot be rasterised.
+func (f *Fetcher) pageIcons(ctx context.Context, pageURL string) []string {
+	resp, err := f.fetch(ctx, http.MethodGet, pageURL, nil)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return nil
+	}
+	doc, err := html.Parse(bytes.NewReader(resp.body))
+	if err != nil {
+		return nil
+	}
+	base := resp.Request.URL.String()
+	if b := findElement(doc, atom.Base); b != nil && attrValue(b, "href") != "" {
+		base = resolveURL(base, attrValue(b, "href"))
+	}
+
+	var icons []pageIcon
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.Link {
+			rel := strings.Fields(strings.ToLower(attrValue(n, "rel")))
+			href := strings.TrimSpace(attrValue(n, "href"))
+			isIcon := slices.Contains(rel, "icon") || slices.Contains(rel, "apple-touch-icon")
+			isVector := attrValue(n, "type") == "image/svg+xml" || strings.HasSuffix(strings.ToLower(href), ".svg")
+			if isIcon && !isVector && href != "" {
+				icons = append(icons, pageIcon{
+					url:  resolveURL(base, href),
+					size: parseIconSize(attrValue(n, "sizes")),
+				})
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	slices.SortStableFunc(icons, func(a, b pageIcon) int {
+		return iconRank(a.size) - iconRank(b.size)
+	})
+	urls := make([]string, 0, len(icons))
+	for _, icon := range icons {
+		urls = append(urls, icon.url)
+	}
+	return urls
+}
+
+// iconRank orders icon sizes by preference: the smallest that is at least
+// IconSize, then those of unknown size, then the largest of the rest.
+func iconRank(size int) int {
+	switch {
+	case size >= IconSize:
+		return size
+	case size == 0:
+		return 1 << 20
+	default:
+		return 1<<21 - size
+	}
+}
+
+// parseIconSize returns the width of the largest size listed in a sizes
+// attribute such as "16x16 32x32".
+func parseIconSize(sizes string) int {
+	var largest int
+	for _, size := range strings.Fields(strings.ToLower(sizes)) {
+		width, _, _ := strings.Cut(size, "x")
+		if n, err := strconv.Atoi(width); err == nil && n > largest {
+			largest = n
+		}
+	}
+	return largest
+}
+
+// normalizeIcon decodes a PNG, GIF, JPEG or ICO image and returns it scaled
+// to fit an IconSize square, encoded as PNG.
+func normalizeIcon(data []byte) ([]byte, error) {
+	var img image.Image
+	var err error
+	if bytes.HasPrefix(data, []byte{0, 0, 1, 0}) {
+		img, err = decodeICO(data)
+	} else {
+		img, err = decodeImage(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	err = png.Encode(&b, scaleImage(img, IconSize))
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// decodeImage decodes a PNG, GIF or JPEG image after checking from its header
+// that it is no larger than maxIconDimension.
+func decodeImage(data []byte) (image.Image, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width > maxIconDimension || config.Height > maxIconDimension {
+		return nil, fmt.Errorf("Icon too large: %dx%d", config.Width, config.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// scaleImage fits img in a size square, keeping its aspect ratio and centring
+// it on a transparent background. Each pixel is the average of the pixels it
+// covers, which keeps small icons legible when large ones are scaled down.
+func scaleImage(img image.Image, size int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	src := img.Bounds()
+	w, h := src.Dx(), src.Dy()
+	if w == 0 || h == 0 {
+		return dst
+	}
+	dw, dh := size, size
+	if w > h {
+		dh = max(1, h*size/w)
+	} else if h > w {
+		dw = max(1, w*size/h)
+	}
+	offsetX, offsetY := (size-dw)/2, (size-dh)/2
+
+	for y := range dh {
+		sy0 := src.Min.Y + y*h/dh
+		sy1 := max(src.Min.Y+(y+1)*h/dh, sy0+1)
+		for x := range dw {
+			sx0 := src.Min.X + x*w/dw
+			sx1 := max(src.Min.X+(x+1)*w/dw, sx0+1)
+
+			var r, g, b, a, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					pr, pg, pb, pa := img.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+			dst.SetRGBA64(offsetX+x, offsetY+y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(b / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+	return dst
+}
+
+// decodeICO decodes the image of an ICO file best suited to IconSize. Images
+// may be stored as PNG or as an uncompressed bitmap of 1, 4, 8, 24 or 32 bits
+// per pixel.
+func decodeICO(data []byte) (image.Image, error) {
+	if len(data) < 6 {
+		return nil, errors.New("Invalid ICO file")
+	}
+	count := int(binary.LittleEndian.Uint16(data[4:6]))
+	if count == 0 || len(data) < 6+16*count {
+		return nil, errors.New("Invalid ICO file")
+	}
+
+	var best []byte
+	var bestSize int
+	for i := range count {
+		entry := data[6+16*i : 6+16*(i+1)]
+		size := int(entry[0])
+		if size == 0 {
+			size = 256
+		}
+		length := int(binary.LittleEndian.Uint32(entry[8:12]))
+		offset := int(binary.LittleEndian.Uint32(entry[12:16]))
+		if offset < 0 || length <= 0 || offset+length > len(data) {
+			continue
+		}
+		if best == nil || iconRank(size) < iconRank(bestSize) {
+			best, bestSize = data[offset:offset+length], size
+		}
+	}
+	if best == nil {
+		return nil, errors.New("Invalid ICO file")
+	}
+
+	if bytes.HasPrefix(best, pngSignature) {
+		return decodeImage(best)
+	}
+	return decodeDIB(best)
+}
+
+// decodeDIB decodes the bitmap of an ICO entry: a BITMAPINFOHEADER, an
+// optional palette, the bottom-up colour rows and a 1-bit transparency mask.
+func decodeDIB(data []byte) (image.Image, error) {
+	if len(data) < 40 {
+		return nil, errors.New("Invalid ICO bitmap")
+	}
+	headerSize := int(binary.LittleEndian.Uint32(data[0:4]))
+	width := int(int32(binary.LittleEndian.Uint32(data[4:8])))
+	// The height covers both the colour rows and the mask.
+	height := int(int32(binary.LittleEndian.Uint32(data[8:12]))) / 2
+	bpp := int(binary.LittleEndian.Uint16(data[14:16]))
+	compression := binary.LittleEndian.Uint32(data[16:20])
+	colorsUsed := int(binary.LittleEndian.Uint32(data[32:36]))
+	if width <= 0 || height <= 0 || width > maxIconDimension || height > maxIconDimension || compression != 0 || headerSize < 40 {
+		return nil, errors.New("Unsupported ICO bitmap")
+	}
+
+	var palette []color.NRGBA
+	switch bpp {
+	case 1, 4, 8:
+		if colorsUsed == 0 {
+			colorsUsed = 1 << bpp
+		}
+		start := headerSize
+		if start+4*colorsUsed > len(data) {
+			return nil, errors.New("Invalid ICO bitmap")
+		}
+		for i := range colorsUsed {
+			p := data[start+4*i:]
+			palette = append(palette, color.NRGBA{R: p[2], G: p[1], B: p[0], A: 0xff})
+		}
+	case 24, 32:
+	default:
+		return nil, errors.New("Unsupported ICO bitmap")
+	}
+
+	pixels := headerSize + 4*len(palette)
+	stride := (width*bpp + 31) / 32 * 4
+	maskStart := pixels + stride*height
+	maskStride := (width + 31) / 32 * 4
+	if maskStart > len(data) {
+		return nil, errors.New("Invalid ICO bitmap")
+	}
+	hasMask := maskStart+maskStride*height <= len(data)
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	hasAlpha := false
+	for y := range height {
+		row := data[pixels+stride*(height-1-y):]
+		for x := range width {
+			var c color.NRGBA
+			switch bpp {
+			case 32:
+				c = color.NRGBA{R: row[4*x+2], G: row[4*x+1], B: row[4*x], A: row[4*x+3]}
+				hasAlpha = hasAlpha || c.A != 0
+			case 24:
+				c = color.NRGBA{R: row[3*x+2], G: row[3*x+1], B: row[3*x], A: 0xff}
+			default:
+				bit := x * bpp
+				index := int(row[bit/8]>>(8-bpp-bit%8)) & (1<<bpp - 1)
+				if index < len(palette) {
+					c = palette[index]
+				}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+
+	// Bitmaps without an alpha channel, or with an empty one, are made
+	// transparent through the mask.
+	if bpp != 32 || !hasAlpha {
+		for y := range height {
+			for x := range width {
+				transparent := false
+				if hasMask {
+					mask := data[maskStart+maskStride*(height-1-y):]
+					transparent = mask[x/8]&(0x80>>(x%8)) != 0
+				}
+				c := img.NRGBAAt(x, y)
+				c.A = 0xff
+				if transparent {
+					c.A = 0
+				}
+				img.SetNRGBA(x, y, c)
+			}
+		}
+	}
+	return img, nil
+}
@@ -0,0 +1,305 @@
+package syndication
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"slices"
+	"testing"
+)
+
+var (
+	red   = color.NRGBA{R: 0xff, A: 0xff}
+	green = color.NRGBA{G: 0xff, A: 0xff}
+	blue  = color.NRGBA{B: 0xff, A: 0xff}
+	white = color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+)
+
+// testDIB encodes pixels, given top row first, as an ICO bitmap of the given
+// depth. Paletted depths get a palette of the colours used. The mask, if
+// any, marks the pixels that are transparent.
+func testDIB(pixels [][]color.NRGBA, bpp int, mask [][]bool) []byte {
+	height, width := len(pixels), len(pixels[0])
+
+	var palette []color.NRGBA
+	if bpp <= 8 {
+		for _, row := range pixels {
+			for _, c := range row {
+				if !slices.Contains(palette, c) {
+					palette = append(palette, c)
+				}
+			}
+		}
+	}
+
+	header := make([]byte, 40)
+	binary.LittleEndian.PutUint32(header[0:4], 40)
+	binary.LittleEndian.PutUint32(header[4:8], uint32(width))
+	binary.LittleEndian.PutUint32(header[8:12], uint32(2*height))
+	binary.LittleEndian.PutUint16(header[12:14], 1)
+	binary.LittleEndian.PutUint16(header[14:16], uint16(bpp))
+	binary.LittleEndian.PutUint32(header[32:36], uint32(len(palette)))
+	b := bytes.NewBuffer(header)
+	for _, c := range palette {
+		b.Write([]byte{c.B, c.G, c.R, 0})
+	}
+
+	stride := (width*bpp + 31) / 32 * 4
+	for y := height - 1; y >= 0; y-- {
+		row := make([]byte, stride)
+		for x, c := range pixels[y] {
+			switch bpp {
+			case 32:
+				copy(row[4*x:], []byte{c.B, c.G, c.R, c.A})
+			case 24:
+				copy(row[3*x:], []byte{c.B, c.G, c.R})
+			default:
+				bit := x * bpp
+				row[bit/8] |= byte(slices.Index(palette, c) << (8 - bpp - bit%8))
+			}
+		}
+		b.Write(row)
+	}
+
+	if mask != nil {
+		maskStride := (width + 31) / 32 * 4
+		for y := height - 1; y >= 0; y-- {
+			row := make([]byte, maskStride)
+			for x, transparent := range mask[y] {
+				if transparent {
+					row[x/8] |= 0x80 >> (x % 8)
+				}
+			}
+			b.Write(row)
+		}
+	}
+	return b.Bytes()
+}
+
+// testICO wraps images in an ICO file. sizes are the sizes recorded in the
+// directory.
+func testICO(sizes []int, images ...[]byte) []byte {
+	var b bytes.Buffer
+	b.Write([]byte{0, 0, 1, 0})
+	binary.Write(&b, binary.LittleEndian, uint16(len(images)))
+	offset := 6 + 16*len(images)
+	for i, img := range images {
+		entry := make([]byte, 16)
+		entry[0], entry[1] = byte(sizes[i]), byte(sizes[i])
+		binary.LittleEndian.PutUint32(entry[8:12], uint32(len(img)))
+		binary.LittleEndian.PutUint32(entry[12:16], uint32(offset))
+		b.Write(entry)
+		offset += len(img)
+	}
+	for _, img := range images {
+		b.Write(img)
+	}
+	return b.Bytes()
+}
+
+func testPNG(t testing.TB, width, height int) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			img.SetNRGBA(x, y, blue)
+		}
+	}
+	var b bytes.Buffer
+	err := png.Encode(&b, img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestDecodeDIB(t *testing.T) {
+	four := [][]color.NRGBA{{red, green}, {blue, white}}
+	two := [][]color.NRGBA{{red, blue}, {blue, red}}
+	mask := [][]bool{{false, true}, {false, false}}
+	masked := func(pixels [][]color.NRGBA) [][]color.NRGBA {
+		out := [][]color.NRGBA{slices.Clone(pixels[0]), slices.Clone(pixels[1])}
+		out[0][1].A = 0
+		return out
+	}
+	translucent := [][]color.NRGBA{{{R: 0xff, A: 0x80}, green}, {blue, {A: 0}}}
+	noAlpha := [][]color.NRGBA{{{R: 0xff}, {G: 0xff}}, {{B: 0xff}, {R: 0xff, G: 0xff, B: 0xff}}}
+
+	tests := []struct {
+		name   string
+		pixels [][]color.NRGBA
+		bpp    int
+		mask   [][]bool
+		want   [][]color.NRGBA
+	}{
+		{"32 bpp with alpha", translucent, 32, mask, translucent},
+		{"32 bpp without alpha", noAlpha, 32, mask, masked(four)},
+		{"32 bpp without alpha or mask", noAlpha, 32, nil, four},
+		{"24 bpp", four, 24, nil, four},
+		{"24 bpp with mask", four, 24, mask, masked(four)},
+		{"8 bpp", four, 8, nil, four},
+		{"8 bpp with mask", four, 8, mask, masked(four)},
+		{"4 bpp", four, 4, nil, four},
+		{"4 bpp with mask", four, 4, mask, masked(four)},
+		{"1 bpp", two, 1, nil, two},
+		{"1 bpp with mask", two, 1, mask, masked(two)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := decodeDIB(testDIB(tt.pixels, tt.bpp, tt.mask))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if img.Bounds() != image.Rect(0, 0, 2, 2) {
+				t.Fatalf("bounds = %v, want 2x2", img.Bounds())
+			}
+			for y, row := range tt.want {
+				for x, want := range row {
+					got := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+					if got != want {
+						t.Errorf("pixel (%d, %d) = %v, want %v", x, y, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeDIBInvalid(t *testing.T) {
+	pixels := [][]color.NRGBA{{red, green}, {blue, white}}
+	for _, bpp := range []int{1, 4, 8, 24, 32} {
+		dib := testDIB(pixels, bpp, [][]bool{{false, false}, {false, false}})
+		// Everything up to the end of the colour rows is required; the mask
+		// is optional.
+		required := len(dib) - 2*4
+		for n := range len(dib) {
+			_, err := decodeDIB(dib[:n])
+			if n < required && err == nil {
+				t.Errorf("%d bpp truncated to %d bytes: decoded without error", bpp, n)
+			}
+		}
+	}
+
+	tests := map[string]func(dib []byte){
+		"oversized width":  func(dib []byte) { binary.LittleEndian.PutUint32(dib[4:8], 2000) },
+		"oversized height": func(dib []byte) { binary.LittleEndian.PutUint32(dib[8:12], 4000) },
+		"zero width":       func(dib []byte) { binary.LittleEndian.PutUint32(dib[4:8], 0) },
+		"negative height":  func(dib []byte) { binary.LittleEndian.PutUint32(dib[8:12], 0xfffffffc) },
+		"compressed":       func(dib []byte) { binary.LittleEndian.PutUint32(dib[16:20], 1) },
+		"16 bpp":           func(dib []byte) { binary.LittleEndian.PutUint16(dib[14:16], 16) },
+		"short header":     func(dib []byte) { binary.LittleEndian.PutUint32(dib[0:4], 12) },
+		"huge header":      func(dib []byte) { binary.LittleEndian.PutUint32(dib[0:4], 0xffffffff) },
+		"huge palette":     func(dib []byte) { binary.LittleEndian.PutUint32(dib[32:36], 0xffffffff) },
+	}
+	for name, corrupt := range tests {
+		dib := testDIB(pixels, 8, nil)
+		corrupt(dib)
+		_, err := decodeDIB(dib)
+		if err == nil {
+			t.Errorf("%s: decoded without error", name)
+		}
+	}
+}
+
+func TestDecodeICO(t *testing.T) {
+	small := testDIB([][]color.NRGBA{{red, red}, {red, red}}, 24, nil)
+	large := testPNG(t, 48, 48)
+
+	// The smallest image at least IconSize wide is picked, whatever its
+	// format and position.
+	for _, ico := range [][]byte{
+		testICO([]int{16, 48}, small, large),
+		testICO([]int{48, 16}, large, small),
+	} {
+		img, err := decodeICO(ico)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if img.Bounds().Dx() != 48 {
+			t.Errorf("picked a %v image, want the 48x48 PNG", img.Bounds())
+		}
+	}
+
+	img, err := decodeICO(testICO([]int{2}, small))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := color.NRGBAModel.Convert(img.At(0, 0)); got != red {
+		t.Errorf("pixel = %v, want red", got)
+	}
+
+	ico := testICO([]int{16, 48}, small, large)
+	for n := range len(ico) {
+		_, err := decodeICO(ico[:n])
+		if err == nil && n < len(ico)-len(large) {
+			t.Errorf("truncated to %d bytes: decoded without error", n)
+		}
+	}
+
+	_, err = decodeICO(testICO([]int{0}, testPNG(t, 2000, 16)))
+	if err == nil {
+		t.Error("decoded a PNG wider than maxIconDimension")
+	}
+	_, err = decodeICO([]byte{0, 0, 1, 0, 0, 0})
+	if err == nil {
+		t.Error("decoded an ICO file without images")
+	}
+}
+
+func TestNormalizeIcon(t *testing.T) {
+	icons := map[string][]byte{
+		"png":  testPNG(t, 64, 16),
+		"ico":  testICO([]int{2}, testDIB([][]color.NRGBA{{red, green}, {blue, white}}, 32, nil)),
+		"tiny": testPNG(t, 1, 1),
+	}
+	for name, data := range icons {
+		icon, err := normalizeIcon(data)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		img, err := png.Decode(bytes.NewReader(icon))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if img.Bounds() != image.Rect(0, 0, IconSize, IconSize) {
+			t.Errorf("%s: bounds = %v, want %dx%d", name, img.Bounds(), IconSize, IconSize)
+		}
+	}
+
+	for _, data := range [][]byte{nil, []byte("GIF89a"), []byte("<html></html>"), testPNG(t, 16, 1025)} {
+		_, err := normalizeIcon(data)
+		if err == nil {
+			t.Errorf("normalizeIcon(%.16q) succeeded, want an error", data)
+		}
+	}
+}
+
+func FuzzNormalizeIcon(f *testing.F) {
+	pixels := [][]color.NRGBA{{red, green}, {blue, white}}
+	for _, bpp := range []int{1, 4, 8, 24, 32} {
+		if bpp == 1 {
+			pixels = [][]color.NRGBA{{red, blue}, {blue, red}}
+		}
+		f.Add(testICO([]int{2}, testDIB(pixels, bpp, [][]bool{{true, false}, {false, true}})))
+	}
+	f.Add(testICO([]int{16}, testPNG(f, 16, 16)))
+	f.Add(testPNG(f, 3, 5))
+	f.Add([]byte{0, 0, 1, 0, 1, 0})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		icon, err := normalizeIcon(data)
+		if err != nil {
+			return
+		}
+		config, err := png.DecodeConfig(bytes.NewReader(icon))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.Width != IconSize || config.Height != IconSize {
+			t.Errorf("icon is %dx%d, want %dx%d", config.Width, config.Height, IconSize, IconSize)
+		}
+	})
+}
@@ -0,0 +1,127 @@
+package syndication
+
+import (
+	"html"
+	"strings"
+)
+
+type JSONFeedAuthor struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type JSONFeedAttachment struct {
+	URL               string `json:"url"`
+	MimeType          string `json:"mime_type"`
+	Title             string `json:"title"`
+	SizeInBytes       int64  `json:"size_in_bytes"`
+	DurationInSeconds int64  `json:"duration_in_seconds"`
+}
+
+type JSONFeedItem struct {
+	ID            string               `json:"id"`
+	URL           string               `json:"url"`
+	ExternalURL   string               `json:"external_url"`
+	Title         string               `json:"title"`
+	ContentHTML   string               `json:"content_html"`
+	ContentText   string               `json:"content_text"`
+	Summary       string               `json:"summary"`
+	DatePublished string               `json:"date_published"`
+	DateModified  string               `json:"date_modified"`
+	Authors       []JSONFeedAuthor     `json:"authors"`
+	Attachments   []JSONFeedAttachment `json:"attachments"`
+	Tags          []string             `json:"tags"`
+	// Deprecated in JSON Feed 1.1 but still common in the wild.
+	Author *JSONFeedAuthor `json:"author"`
+}
+
+func (jfi JSONFeedItem) toFeedEntry() *FeedEntry {
+	link := jfi.URL
+	if link == "" {
+		link = jfi.ExternalURL
+	}
+
+	content := strings.TrimSpace(jfi.ContentHTML)
+	if content == "" && jfi.ContentText != "" {
+		content = html.EscapeString(strings.TrimSpace(jfi.ContentText))
+	}
+
+	authors := jfi.Authors
+	if len(authors) == 0 && jfi.Author != nil {
+		authors = []JSONFeedAuthor{*jfi.Author}
+	}
+	var names []string
+	for _, author := range authors {
+		if name := strings.TrimSpace(author.Name); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	var enclosures []Enclosure
+	for _, a := range jfi.Attachments {
+		if a.URL == "" {
+			continue
+		}
+		enclosures = append(enclosures, Enclosure{
+			URL:      a.URL,
+			Type:     a.MimeType,
+			Title:    strings.TrimSpace(a.Title),
+			Length:   a.SizeInBytes,
+			Duration: a.DurationInSeconds,
+		})
+	}
+
+	return &FeedEntry{
+		ID:          strings.TrimSpace(jfi.ID),
+		Title:       strings.TrimSpace(jfi.Title),
+		Description: html.EscapeString(strings.TrimSpace(jfi.Summary)),
+		Published:   normalizeDate(jfi.DatePublished, jfi.DateModified),
+		Updated:     normalizeDate(jfi.DateModified),
+		Author:      strings.Join(names, ", "),
+		Link:        strings.TrimSpace(link),
+		Content:     content,
+		Categories:  normalizeCategories(jfi.Tags),
+		Enclosures:  enclosures,
+	}
+}
+
+type JSONFeedHub struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type JSONFeedDocument struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Description string         `json:"description"`
+	Icon        string         `json:"icon"`
+	Favicon     string         `json:"favicon"`
+	Hubs        []JSONFeedHub  `json:"hubs"`
+	Items       []JSONFeedItem `json:"items"`
+}
+
+func (jf JSONFeedDocument) toFeed() *Feed {
+	var entries []FeedEntry
+	for _, item := range jf.Items {
+		entries = append(entries, *item.toFeedEntry())
+	}
+	var hubURL string
+	for _, hub := range jf.Hubs {
+		if strings.EqualFold(hub.Type, "WebSub") && hubURL == "" {
+			hubURL = strings.TrimSpace(hub.URL)
+		}
+	}
+	return &Feed{
+		Title:    strings.TrimSpace(jf.Title),
+		Subtitle: strings.TrimSpace(jf.Description),
+		FeedURL:  jf.FeedURL,
+		SiteURL:  jf.HomePageURL,
+		IconURL:  firstNonEmpty(jf.Favicon, jf.Icon),
+		HubURL:   hubURL,
+		TopicURL: strings.TrimSpace(jf.FeedURL),
+		Entries:  entries,
+		Type:     JSONFeed,
+	}
+}
@@ -0,0 +1,138 @@
+package syndication
+
+import (
+	"html"
+	"strings"
+)
+
+type mediaContent struct {
+	URL      string `xml:"url,attr"`
+	Type     string `xml:"type,attr"`
+	Medium   string `xml:"medium,attr"`
+	FileSize string `xml:"fileSize,attr"`
+	Duration string `xml:"duration,attr"`
+}
+
+type mediaThumbnail struct {
+	URL string `xml:"url,attr"`
+}
+
+type mediaElements struct {
+	Title       string           `xml:"http://search.yahoo.com/mrss/ title"`
+	Description string           `xml:"http://search.yahoo.com/mrss/ description"`
+	Thumbnails  []mediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	Contents    []mediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+}
+
+// mediaModule holds the Media RSS elements of an item, either directly on it
+// or wrapped in a media:group, along with YouTube's video id.
+//
+// It must be embedded before any unqualified title or description field,
+// since encoding/xml assigns an element to the first field that matches it.
+type mediaModule struct {
+	mediaElements
+	Group   mediaElements `xml:"http://search.yahoo.com/mrss/ group"`
+	VideoID string        `xml:"http://www.youtube.com/xml/schemas/2015 videoId"`
+}
+
+// Media is the structured media attached to an entry.
+type Media struct {
+	Title        string
+	Description  string
+	ThumbnailURL string
+	VideoID      string
+}
+
+func (mm mediaModule) toMedia() Media {
+	media := Media{
+		Title:       firstNonEmpty(mm.Title, mm.Group.Title),
+		Description: firstNonEmpty(mm.Description, mm.Group.Description),
+		VideoID:     strings.TrimSpace(mm.VideoID),
+	}
+	for _, thumbnail := range append(mm.Thumbnails, mm.Group.Thumbnails...) {
+		if thumbnail.URL != "" {
+			media.ThumbnailURL = strings.TrimSpace(thumbnail.URL)
+			break
+		}
+	}
+	return media
+}
+
+// enclosures returns the playable audio and video files among the media
+// contents. Other contents, such as YouTube's Flash player URL, are skipped.
+func (mm mediaModule) enclosures(title string) []Enclosure {
+	var enclosures []Enclosure
+	for _, content := range append(mm.Contents, mm.Group.Contents...) {
+		medium := content.Medium
+		if medium == "" {
+			medium, _, _ = strings.Cut(content.Type, "/")
+		}
+		if content.URL == "" || (medium != "audio" && medium != "video") {
+			continue
+		}
+		if content.Type == "" || strings.HasPrefix(content.Type, "application/") {
+			continue
+		}
+		enclosures = append(enclosures, Enclosure{
+			URL:      strings.TrimSpace(content.URL),
+			Type:     strings.TrimSpace(content.Type),
+			Title:    strings.TrimSpace(title),
+			Length:   parseLength(content.FileSize),
+			Duration: parseDuration(content.Duration),
+		})
+	}
+	return enclosures
+}
+
+// apply fills in what the entry is missing from its media: the link and id
+// of YouTube videos, a summary, and content.
+func (mm mediaModule) apply(fe *FeedEntry) {
+	fe.Media = mm.toMedia()
+
+	if fe.Media.VideoID != "" {
+		if fe.Link == "" {
+			fe.Link = "https://www.youtube.com/watch?v=" + fe.Media.VideoID
+		}
+		if fe.ID == "" {
+			fe.ID = "yt:video:" + fe.Media.VideoID
+		}
+	}
+
+	if fe.Media.Description != "" {
+		if fe.Description == "" {
+			fe.Description = textToHTML(fe.Media.Description)
+		}
+		if fe.Content == "" {
+			fe.Content = textToHTML(fe.Media.Description)
+		}
+	}
+
+	for _, enclosure := range mm.enclosures(fe.Media.Title) {
+		if !hasEnclosure(fe.Enclosures, enclosure.URL) {
+			fe.Enclosures = append(fe.Enclosures, enclosure)
+		}
+	}
+}
+
+func hasEnclosure(enclosures []Enclosure, url string) bool {
+	for _, enclosure := range enclosures {
+		if enclosure.URL == url {
+			return true
+		}
+	}
+	return false
+}
+
+// textToHTML escapes plain text and keeps its line breaks.
+func textToHTML(text string) string {
+	return strings.ReplaceAll(html.EscapeString(strings.TrimSpace(text)), "\n", "<br>\n")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
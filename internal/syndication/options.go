@@ -0,0 +1,225 @@
+package syndication
+
+import (
+	"bytes"
+	"crypto/tls"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var ErrInvalidRequestOptions = errors.New("Invalid request options")
+
+// RequestOptions customise the requests made for a single feed, for feeds
+// that need authentication or are only reachable from a private network.
+// Headers are added to the requests made to the feed's host, Username and
+// Password are sent there as HTTP basic credentials and Cookie as the Cookie
+// header. UserAgent replaces the
+// fetcher's. ProxyURL is an http, https or socks5 proxy the requests go
+// through, and InsecureSkipVerify accepts any TLS certificate, for hosts with
+// self-signed ones.
+type RequestOptions struct {
+	Headers            map[string]string `json:"headers,omitempty"`
+	Username           string            `json:"username,omitempty"`
+	Password           string            `json:"password,omitempty"`
+	Cookie             string            `json:"cookie,omitempty"`
+	UserAgent          string            `json:"user_agent,omitempty"`
+	ProxyURL           string            `json:"proxy_url,omitempty"`
+	InsecureSkipVerify bool              `json:"insecure_skip_verify,omitempty"`
+}
+
+// IsZero reports whether no options are set.
+func (o RequestOptions) IsZero() bool {
+	return len(o.Headers) == 0 && o.Username == "" && o.Password == "" && o.Cookie == "" &&
+		o.UserAgent == "" && o.ProxyURL == "" && !o.InsecureSkipVerify
+}
+
+// Validate reports whether the headers are well formed and the proxy, if
+// any, is a URL of a supported scheme.
+func (o RequestOptions) Validate() error {
+	for name, value := range o.Headers {
+		if !validHeaderName(name) || !validHeaderValue(value) {
+			return fmt.Errorf("%w: invalid header %q", ErrInvalidRequestOptions, name)
+		}
+	}
+	for _, value := range []string{o.Username, o.Password, o.Cookie, o.UserAgent} {
+		if !validHeaderValue(value) {
+			return fmt.Errorf("%w: values must not contain control characters", ErrInvalidRequestOptions)
+		}
+	}
+	if o.ProxyURL != "" {
+		_, err := o.proxy()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validHeaderName reports whether name is an HTTP token.
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if c > 0x7f || !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("!#$%&'*+-.^_`|~", c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// validHeaderValue reports whether value can be sent in a header, that is
+// whether it has no control characters other than tabs.
+func validHeaderValue(value string) bool {
+	for _, c := range value {
+		if c < ' ' && c != '\t' || c == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+func (o RequestOptions) proxy() (*url.URL, error) {
+	u, err := url.Parse(o.ProxyURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("%w: invalid proxy URL", ErrInvalidRequestOptions)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+		return u, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported proxy scheme %q", ErrInvalidRequestOptions, u.Scheme)
+	}
+}
+
+// Value stores the options as JSON, or as an empty string when none are set.
+func (o RequestOptions) Value() (driver.Value, error) {
+	if o.IsZero() {
+		return "", nil
+	}
+	b, err := json.Marshal(o)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan reads options stored by Value.
+func (o *RequestOptions) Scan(src any) error {
+	var b []byte
+	switch v := src.(type) {
+	case nil:
+	case string:
+		b = []byte(v)
+	case []byte:
+		b = v
+	default:
+		return fmt.Errorf("Cannot scan %T into RequestOptions", src)
+	}
+	*o = RequestOptions{}
+	if len(bytes.TrimSpace(b)) == 0 {
+		return nil
+	}
+	return json.Unmarshal(b, o)
+}
+
+// WithOptions returns a fetcher that makes the same requests as f with opts
+// applied. The headers and credentials are only sent to the host of feedURL,
+// so that they do not leak to the pages and images a feed links to, while the
+// proxy, TLS and User-Agent options apply to every request. Proxy and TLS
+// options need the client's transport to be an *http.Transport, or nil for
+// the default one. The returned fetcher keeps its own discovery cache, so that
+// what a feed's credentials reveal is not reused for other feeds.
+func (f *Fetcher) WithOptions(opts RequestOptions, feedURL string) (*Fetcher, error) {
+	if opts.IsZero() {
+		return f, nil
+	}
+	err := opts.Validate()
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(feedURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("%w: invalid feed URL", ErrInvalidRequestOptions)
+	}
+
+	client := f.Client
+	if opts.ProxyURL != "" || opts.InsecureSkipVerify {
+		var transport *http.Transport
+		switch t := client.Transport.(type) {
+		case nil:
+			transport = http.DefaultTransport.(*http.Transport).Clone()
+		case *http.Transport:
+			transport = t.Clone()
+		default:
+			return nil, fmt.Errorf("%w: proxy and TLS settings are not supported by the HTTP client", ErrInvalidRequestOptions)
+		}
+		if opts.ProxyURL != "" {
+			proxy, _ := opts.proxy()
+			transport.Proxy = http.ProxyURL(proxy)
+		}
+		if opts.InsecureSkipVerify {
+			if transport.TLSClientConfig == nil {
+				transport.TLSClientConfig = &tls.Config{}
+			}
+			transport.TLSClientConfig.InsecureSkipVerify = true
+		}
+		c := *client
+		c.Transport = transport
+		client = &c
+	}
+
+	userAgent := f.UserAgent
+	if opts.UserAgent != "" {
+		userAgent = opts.UserAgent
+	}
+	return &Fetcher{
+		Client:            client,
+		UserAgent:         userAgent,
+		Timeout:           f.Timeout,
+		MaxBodySize:       f.MaxBodySize,
+		MaxRedirects:      f.MaxRedirects,
+		DiscoveryCacheTTL: f.DiscoveryCacheTTL,
+		options:           opts,
+		optionsHost:       strings.ToLower(u.Host),
+	}, nil
+}
+
+// applyOptions sets the headers and credentials of the fetcher's options on
+// a request to the host they belong to.
+func (f *Fetcher) applyOptions(req *http.Request) {
+	if !strings.EqualFold(req.URL.Host, f.optionsHost) {
+		return
+	}
+	for name, value := range f.options.Headers {
+		req.Header.Set(name, value)
+	}
+	if f.options.Cookie != "" {
+		req.Header.Set("Cookie", f.options.Cookie)
+	}
+	if f.options.Username != "" || f.options.Password != "" {
+		req.SetBasicAuth(f.options.Username, f.options.Password)
+	}
+}
+
+// removeOptions deletes the headers and credentials of the fetcher's options
+// from a redirected request to another host.
+func (f *Fetcher) removeOptions(req *http.Request) {
+	if strings.EqualFold(req.URL.Host, f.optionsHost) {
+		return
+	}
+	for name := range f.options.Headers {
+		req.Header.Del(name)
+	}
+	if f.options.Cookie != "" {
+		req.Header.Del("Cookie")
+	}
+	if f.options.Username != "" || f.options.Password != "" {
+		req.Header.Del("Authorization")
+	}
+}
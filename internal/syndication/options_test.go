@@ -0,0 +1,149 @@
+package syndication
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// headerRecorder is a server that records the headers of the requests it
+// receives by path.
+type headerRecorder struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	headers map[string]http.Header
+}
+
+func newHeaderRecorder(t *testing.T, handler http.HandlerFunc) *headerRecorder {
+	rec := &headerRecorder{headers: map[string]http.Header{}}
+	rec.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec.mu.Lock()
+		rec.headers[r.URL.Path] = r.Header.Clone()
+		rec.mu.Unlock()
+		handler(w, r)
+	}))
+	t.Cleanup(rec.Close)
+	return rec
+}
+
+func (rec *headerRecorder) header(path string) http.Header {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return rec.headers[path]
+}
+
+func TestWithOptionsLimitsCredentialsToFeedHost(t *testing.T) {
+	page := `<html><body><article><p>A paragraph of article text, long enough to be kept, with a comma or two.</p></article></body></html>`
+	other := newHeaderRecorder(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(page))
+	})
+	feed := newHeaderRecorder(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/away":
+			http.Redirect(w, r, other.URL+"/redirected", http.StatusFound)
+		case "/here":
+			http.Redirect(w, r, "/article", http.StatusFound)
+		default:
+			w.Write([]byte(page))
+		}
+	})
+
+	fetcher, err := NewFetcher(nil, "sammler-test", 5*time.Second, 1<<20).WithOptions(RequestOptions{
+		Headers:  map[string]string{"X-Token": "secret"},
+		Username: "reader",
+		Password: "hunter2",
+		Cookie:   "session=abc",
+	}, feed.URL+"/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"/away", "/here"} {
+		_, err = fetcher.FetchArticle(t.Context(), feed.URL+path)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = fetcher.FetchArticle(t.Context(), other.URL+"/article")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = fetcher.FetchIcon(t.Context(), other.URL+"/icon.png", "")
+
+	sent := []struct {
+		header http.Header
+		want   bool
+	}{
+		{feed.header("/away"), true},
+		{feed.header("/here"), true},
+		{feed.header("/article"), true},
+		{other.header("/redirected"), false},
+		{other.header("/article"), false},
+		{other.header("/icon.png"), false},
+	}
+	for i, tt := range sent {
+		if tt.header == nil {
+			t.Fatalf("request %d was not made", i)
+		}
+		user, password, _ := (&http.Request{Header: tt.header}).BasicAuth()
+		got := map[string]string{
+			"X-Token":  tt.header.Get("X-Token"),
+			"Cookie":   tt.header.Get("Cookie"),
+			"username": user,
+			"password": password,
+		}
+		want := map[string]string{"X-Token": "secret", "Cookie": "session=abc", "username": "reader", "password": "hunter2"}
+		for key, value := range got {
+			if tt.want && value != want[key] {
+				t.Errorf("request %d: %s = %q, want %q", i, key, value, want[key])
+			} else if !tt.want && value != "" {
+				t.Errorf("request %d: %s = %q was sent to another host", i, key, value)
+			}
+		}
+	}
+}
+
+func TestWithOptions(t *testing.T) {
+	srv := newHeaderRecorder(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><body><p>A paragraph of article text, long enough to be kept, with a comma.</p></body></html>`))
+	})
+	base := NewFetcher(nil, "sammler-test", 5*time.Second, 1<<20)
+
+	same, err := base.WithOptions(RequestOptions{}, srv.URL)
+	if err != nil || same != base {
+		t.Errorf("WithOptions without options = %p, %v; want the fetcher itself", same, err)
+	}
+
+	fetcher, err := base.WithOptions(RequestOptions{UserAgent: "custom-agent"}, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = fetcher.FetchArticle(t.Context(), srv.URL+"/article")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ua := srv.header("/article").Get("User-Agent"); ua != "custom-agent" {
+		t.Errorf("User-Agent = %q, want custom-agent", ua)
+	}
+
+	for _, opts := range []RequestOptions{
+		{Headers: map[string]string{"Bad Name": "x"}},
+		{Headers: map[string]string{"X-Token": "a\nb"}},
+		{Cookie: "a=b\r\nX-Injected: 1"},
+		{ProxyURL: "ftp://proxy.example.com"},
+		{ProxyURL: "not a url"},
+	} {
+		_, err := base.WithOptions(opts, srv.URL)
+		if !errors.Is(err, ErrInvalidRequestOptions) {
+			t.Errorf("%+v: got %v, want ErrInvalidRequestOptions", opts, err)
+		}
+	}
+	_, err = base.WithOptions(RequestOptions{Cookie: "a=b"}, "/relative")
+	if !errors.Is(err, ErrInvalidRequestOptions) {
+		t.Errorf("relative feed URL: got %v, want ErrInvalidRequestOptions", err)
+	}
+}
@@ -2,78 +2,152 @@ package syndication
 
 import (
 	"bytes"
-	"encoding/xml"
+	"context"
+	"encoding/json"
 	"errors"
-	"io"
+	"fmt"
 	"net/http"
 )
 
 var ErrFeedNotFound = errors.New("No feed found for given URL")
 var ErrFeedNotSupported = errors.New("Unsupported feed type")
+var ErrNotModified = errors.New("Feed not modified")
+var ErrFeedGone = errors.New("Feed no longer exists")
 
-func resolveFeedURL(url string) (string, error) {
-	isFeed, err := isFeedURL(url)
+// DiscoverFeeds returns the feeds available at url. A feed URL is returned as
+// the only candidate; for a web page, every feed it advertises is returned.
+// Results, including finding no feed, are cached for DiscoveryCacheTTL.
+func (f *Fetcher) DiscoverFeeds(ctx context.Context, url string) ([]FeedCandidate, error) {
+	if d, ok := f.discoveries.get(url); ok {
+		return d.candidates, d.err
+	}
+	candidates, err := f.discover(ctx, url)
+	if err == nil || errors.Is(err, ErrFeedNotFound) {
+		f.discoveries.put(url, candidates, err, f.DiscoveryCacheTTL)
+	}
+	return candidates, err
+}
+
+func (f *Fetcher) discover(ctx context.Context, url string) ([]FeedCandidate, error) {
+	isFeed, err := f.isFeedURL(ctx, url)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if isFeed {
-		return url, nil
+		return []FeedCandidate{{URL: url}}, nil
 	}
+	return f.discoverFeeds(ctx, url)
+}
 
-	source, err := discoverFeedURL(url)
+func (f *Fetcher) resolveFeedURL(ctx context.Context, url string) (string, error) {
+	candidates, err := f.DiscoverFeeds(ctx, url)
 	if err != nil {
 		return "", err
 	}
-	return source, nil
+	return candidates[0].URL, nil
 }
 
-func parseFeed(data []byte, feedURL string) (*Feed, error) {
-	feedType, err := detectFeedType(data)
+// parseFeed decodes a feed document of any supported type. charset is the one
+// given by the Content-Type the document was served with, if any.
+func parseFeed(data []byte, feedURL, charset string) (*Feed, error) {
+	if isJSONFeed(data) {
+		return decodeFeed(data, JSONFeed, feedURL, charset)
+	}
+
+	rootElement, err := detectFeedType(data, charset)
 	if err != nil {
 		return nil, err
 	}
 
-	decoder := xml.NewDecoder(bytes.NewReader(data))
-	switch feedType {
+	switch rootElement {
 	case "rss":
+		return decodeFeed(data, RSS, feedURL, charset)
+	case "feed":
+		return decodeFeed(data, Atom, feedURL, charset)
+	case "RDF":
+		return decodeFeed(data, RDF, feedURL, charset)
+	default:
+		return nil, ErrFeedNotSupported
+	}
+}
+
+// decodeFeed decodes a feed document of a known type. feedURL is the address
+// the document was fetched from. It is used when the feed does not declare
+// its own and to resolve relative links. charset is the one given by the
+// Content-Type the document was served with, if any.
+func decodeFeed(data []byte, ft FeedType, feedURL, charset string) (*Feed, error) {
+	var feed *Feed
+	decoder := newXMLDecoder(data, charset)
+	switch ft {
+	case RSS:
 		f := RSSFeed{}
 		err := decoder.Decode(&f)
 		if err != nil {
 			return nil, err
 		}
-		// HACK: manually assign the feed link to the Feed item
-		f.Channel.AtomLink = feedURL
-		return f.toFeed(), nil
-	case "feed":
+		feed = f.toFeed()
+	case Atom:
 		f := AtomFeed{}
 		err := decoder.Decode(&f)
 		if err != nil {
 			return nil, err
 		}
-		return f.toFeed(), nil
+		feed = f.toFeed()
+	case RDF:
+		f := RDFFeed{}
+		err := decoder.Decode(&f)
+		if err != nil {
+			return nil, err
+		}
+		feed = f.toFeed()
+	case JSONFeed:
+		f := JSONFeedDocument{}
+		err := json.Unmarshal(bytes.TrimPrefix(data, utf8BOM), &f)
+		if err != nil {
+			return nil, err
+		}
+		feed = f.toFeed()
 	default:
 		return nil, ErrFeedNotSupported
 	}
-}
 
-func ExtractFeedDetails(url string) (*Feed, error) {
+	if feed.FeedURL == "" {
+		feed.FeedURL = feedURL
+	}
+	for i := range feed.Entries {
+		if len(feed.Entries[i].Categories) == 0 {
+			feed.Entries[i].Categories = feed.categories
+		}
+	}
+	feed.resolveURLs(feedURL)
+	if feed.Title == "" {
+		feed.Title = hostname(firstNonEmpty(feed.SiteURL, feed.FeedURL))
+	}
+	return feed, nil
+}
 
-	source, err := resolveFeedURL(url)
+func (f *Fetcher) ExtractFeedDetails(ctx context.Context, url string) (*Feed, error) {
+	source, err := f.resolveFeedURL(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := http.Get(source)
+	resp, err := f.fetch(ctx, http.MethodGet, source, nil)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unexpected status code: %d", resp.StatusCode)
+	}
+	if resp.movedTo != "" {
+		source = resp.movedTo
+	}
 
-	body, err := io.ReadAll(resp.Body)
+	feed, err := parseFeed(resp.body, source, contentTypeCharset(resp.Header.Get("Content-Type")))
 	if err != nil {
 		return nil, err
 	}
-
-	return parseFeed(body, source)
-
+	feed.CacheValidators = cacheValidatorsFromResponse(resp)
+	applyLinkHeaders(feed, resp)
+	return feed, nil
 }
@@ -0,0 +1,67 @@
+package syndication
+
+import "strings"
+
+type RDFFeedEntry struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	Date        string   `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Encoded     string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Subjects    []string `xml:"http://purl.org/dc/elements/1.1/ subject"`
+	About       string   `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+}
+
+func (rfe RDFFeedEntry) toFeedEntry() *FeedEntry {
+	content := strings.TrimSpace(rfe.Encoded)
+	if content == "" {
+		content = strings.TrimSpace(rfe.Description)
+	}
+
+	return &FeedEntry{
+		ID:          strings.TrimSpace(rfe.About),
+		Title:       strings.TrimSpace(rfe.Title),
+		Description: strings.TrimSpace(rfe.Description),
+		Published:   normalizeDate(rfe.Date),
+		Author:      strings.TrimSpace(rfe.Creator),
+		Link:        strings.TrimSpace(rfe.Link),
+		Content:     content,
+		Categories:  normalizeCategories(rfe.Subjects),
+	}
+}
+
+// RDFFeed is an RSS 1.0 document. Unlike RSS 2.0 the items are siblings of
+// the channel rather than its children.
+type RDFFeed struct {
+	Channel struct {
+		Title       string   `xml:"title"`
+		Link        string   `xml:"link"`
+		Description string   `xml:"description"`
+		Subjects    []string `xml:"http://purl.org/dc/elements/1.1/ subject"`
+		syndicationModule
+	} `xml:"channel"`
+	Image struct {
+		URL string `xml:"url"`
+	} `xml:"image"`
+	Items []RDFFeedEntry `xml:"item"`
+}
+
+func (rf RDFFeed) toFeed() *Feed {
+	var entries []FeedEntry
+	for _, entry := range rf.Items {
+		entries = append(entries, *entry.toFeedEntry())
+	}
+	return &Feed{
+		Title:    strings.TrimSpace(rf.Channel.Title),
+		Subtitle: strings.TrimSpace(rf.Channel.Description),
+		SiteURL:  strings.TrimSpace(rf.Channel.Link),
+		IconURL:  strings.TrimSpace(rf.Image.URL),
+		Entries:  entries,
+		Type:     RDF,
+		UpdateHints: UpdateHints{
+			UpdatePeriod: rf.Channel.updatePeriod(),
+		},
+		categories: normalizeCategories(rf.Channel.Subjects),
+	}
+}
@@ -0,0 +1,307 @@
+package syndication
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var ErrArticleNotFound = errors.New("No article content found")
+
+// The class and id patterns below follow Mozilla's Readability, which this
+// extractor is a much simplified version of.
+var (
+	unlikelyCandidatePattern = regexp.MustCompile(`(?i)-ad-|ai2html|banner|breadcrumbs|combx|comment|community|cover-wrap|disqus|extra|footer|gdpr|header|legends|menu|related|remark|replies|rss|shoutbox|sidebar|skyscraper|social|sponsor|supplemental|ad-break|agegate|pagination|pager|popup|yom-remote|share|newsletter|cookie`)
+	maybeCandidatePattern    = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positiveClassPattern     = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|post|text|blog|story`)
+	negativeClassPattern     = regexp.MustCompile(`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|contact|foot|footer|footnote|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+)
+
+// strippedElements never hold article text and are removed before scoring.
+var strippedElements = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Iframe:   true,
+	atom.Form:     true,
+	atom.Nav:      true,
+	atom.Aside:    true,
+	atom.Footer:   true,
+	atom.Svg:      true,
+	atom.Button:   true,
+	atom.Input:    true,
+	atom.Select:   true,
+	atom.Textarea: true,
+	atom.Object:   true,
+	atom.Embed:    true,
+	atom.Link:     true,
+	atom.Meta:     true,
+}
+
+// blockElements are the elements that stop a div from being scored as a
+// paragraph of its own.
+var blockElements = map[atom.Atom]bool{
+	atom.Address:    true,
+	atom.Article:    true,
+	atom.Blockquote: true,
+	atom.Div:        true,
+	atom.Dl:         true,
+	atom.Figure:     true,
+	atom.H1:         true,
+	atom.H2:         true,
+	atom.H3:         true,
+	atom.H4:         true,
+	atom.H5:         true,
+	atom.H6:         true,
+	atom.Ol:         true,
+	atom.P:          true,
+	atom.Pre:        true,
+	atom.Section:    true,
+	atom.Table:      true,
+	atom.Ul:         true,
+}
+
+// FetchArticle downloads the page at pageURL and extracts its main content.
+func (f *Fetcher) FetchArticle(ctx context.Context, pageURL string) (string, error) {
+	resp, err := f.fetch(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Unexpected status code: %d", resp.StatusCode)
+	}
+	return ExtractArticle(resp.body, resp.Request.URL.String())
+}
+
+// ExtractArticle returns the main content of an HTML page as HTML, with its
+// links made absolute against pageURL. Blocks of text are scored by their
+// length and punctuation, the scores are given to their ancestors, and the
+// best scoring element is kept along with the siblings that score close to
+// it.
+func ExtractArticle(page []byte, pageURL string) (string, error) {
+	doc, err := html.Parse(bytes.NewReader(page))
+	if err != nil {
+		return "", err
+	}
+	root := findElement(doc, atom.Body)
+	if root == nil {
+		root = doc
+	}
+	removeUnlikelyNodes(root)
+
+	scores := map[*html.Node]float64{}
+	var candidates []*html.Node
+	addScore := func(n *html.Node, score float64) {
+		if n == nil || n.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			scores[n] = initialScore(n)
+			candidates = append(candidates, n)
+		}
+		scores[n] += score
+	}
+
+	for _, n := range scoredBlocks(root) {
+		text := strings.TrimSpace(textContent(n))
+		if len(text) < 25 {
+			continue
+		}
+		score := 1 + float64(strings.Count(text, ",")) + min(float64(len(text)/100), 3)
+		addScore(n.Parent, score)
+		if n.Parent != nil {
+			addScore(n.Parent.Parent, score/2)
+		}
+	}
+
+	var top *html.Node
+	var topScore float64
+	for _, n := range candidates {
+		scores[n] *= 1 - linkDensity(n)
+		if top == nil || scores[n] > topScore {
+			top, topScore = n, scores[n]
+		}
+	}
+	if top == nil {
+		return "", ErrArticleNotFound
+	}
+
+	threshold := max(10, topScore*0.2)
+	var b strings.Builder
+	for sibling := firstSibling(top); sibling != nil; sibling = sibling.NextSibling {
+		if sibling.Type != html.ElementNode {
+			continue
+		}
+		if sibling != top {
+			score, scored := scores[sibling]
+			if !(scored && score >= threshold) && !isContentParagraph(sibling) {
+				continue
+			}
+		}
+		err = html.Render(&b, sibling)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	content := strings.TrimSpace(b.String())
+	if content == "" {
+		return "", ErrArticleNotFound
+	}
+	return resolveContentURLs(content, pageURL), nil
+}
+
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func firstSibling(n *html.Node) *html.Node {
+	if n.Parent == nil {
+		return n
+	}
+	return n.Parent.FirstChild
+}
+
+// removeUnlikelyNodes removes the elements that never hold the article and
+// those whose class or id suggests navigation, comments or advertising.
+func removeUnlikelyNodes(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.CommentNode || (c.Type == html.ElementNode && isUnlikely(c)) {
+			n.RemoveChild(c)
+		} else {
+			removeUnlikelyNodes(c)
+		}
+		c = next
+	}
+}
+
+func isUnlikely(n *html.Node) bool {
+	if strippedElements[n.DataAtom] {
+		return true
+	}
+	if n.DataAtom == atom.Body || n.DataAtom == atom.Article || n.DataAtom == atom.Main {
+		return false
+	}
+	match := attrValue(n, "class") + " " + attrValue(n, "id")
+	return unlikelyCandidatePattern.MatchString(match) && !maybeCandidatePattern.MatchString(match)
+}
+
+// scoredBlocks returns the elements whose text is scored: paragraphs and
+// similar blocks, and divs that hold only inline content.
+func scoredBlocks(n *html.Node) []*html.Node {
+	var blocks []*html.Node
+	if n.Type == html.ElementNode {
+		switch n.DataAtom {
+		case atom.P, atom.Pre, atom.Td, atom.Blockquote:
+			blocks = append(blocks, n)
+		case atom.Div:
+			if !hasBlockChild(n) {
+				blocks = append(blocks, n)
+			}
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		blocks = append(blocks, scoredBlocks(c)...)
+	}
+	return blocks
+}
+
+func hasBlockChild(n *html.Node) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && blockElements[c.DataAtom] {
+			return true
+		}
+	}
+	return false
+}
+
+func initialScore(n *html.Node) float64 {
+	var score float64
+	switch n.DataAtom {
+	case atom.Div, atom.Article, atom.Main:
+		score = 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		score = 3
+	case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li, atom.Form:
+		score = -3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score = -5
+	}
+	return score + classWeight(n)
+}
+
+func classWeight(n *html.Node) float64 {
+	var weight float64
+	for _, value := range []string{attrValue(n, "class"), attrValue(n, "id")} {
+		if value == "" {
+			continue
+		}
+		if negativeClassPattern.MatchString(value) {
+			weight -= 25
+		}
+		if positiveClassPattern.MatchString(value) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// linkDensity is the share of an element's text that is inside links.
+func linkDensity(n *html.Node) float64 {
+	textLength := len(strings.TrimSpace(textContent(n)))
+	if textLength == 0 {
+		return 0
+	}
+	var linkLength int
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.A {
+			linkLength += len(strings.TrimSpace(textContent(n)))
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return float64(linkLength) / float64(textLength)
+}
+
+// isContentParagraph reports whether an unscored sibling of the article is a
+// paragraph of prose that belongs with it.
+func isContentParagraph(n *html.Node) bool {
+	if n.DataAtom != atom.P {
+		return false
+	}
+	text := strings.TrimSpace(textContent(n))
+	density := linkDensity(n)
+	if len(text) > 80 {
+		return density < 0.25
+	}
+	return len(text) > 0 && density == 0 && strings.HasSuffix(text, ".")
+}
+
+func attrValue(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,60 @@
+package syndication
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExtractArticle(t *testing.T) {
+	page, err := os.ReadFile(filepath.Join("testdata", "article", "news.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := ExtractArticle(page, "https://gazette.example/2025/06/rivers")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"After three dry summers",
+		"well above the ten-year average",
+		"Local councils, which had imposed strict limits",
+		`href="https://gazette.example/institutes/water"`,
+		`src="https://gazette.example/2025/06/images/river.jpg"`,
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("content lacks %q:\n%s", want, content)
+		}
+	}
+	for _, unwanted := range []string{
+		"Science",
+		"Most read",
+		"kettle",
+		"swimming in that river",
+		"newsletter",
+		"Copyright",
+		"analytics",
+	} {
+		if strings.Contains(content, unwanted) {
+			t.Errorf("content has %q:\n%s", unwanted, content)
+		}
+	}
+}
+
+func TestExtractArticleNotFound(t *testing.T) {
+	pages := []string{
+		"",
+		"<html><body></body></html>",
+		"<html><body><p>Too short.</p><nav><p>A long run of navigation text, that is not an article at all, by any measure.</p></nav></body></html>",
+		"<html><body><script>var text = 'a long script, with commas, that is never shown to a reader';</script></body></html>",
+	}
+	for _, page := range pages {
+		_, err := ExtractArticle([]byte(page), "https://gazette.example/")
+		if !errors.Is(err, ErrArticleNotFound) {
+			t.Errorf("%q: got %v, want ErrArticleNotFound", page, err)
+		}
+	}
+}
@@ -1,73 +1,115 @@
 package syndication
 
-import (
-	"fmt"
-	"strings"
-	"time"
-)
+import "strings"
 
 type RSSFeedEntry struct {
-	Title       string `xml:"title"`
-	Description string `xml:"description"`
-	Published   string `xml:"pubDate"`
-	Link        string `xml:"link"`
+	mediaModule
+	xmlBase
+	GUID        string         `xml:"guid"`
+	Title       string         `xml:"title"`
+	Description string         `xml:"description"`
+	Encoded     string         `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Published   string         `xml:"pubDate"`
+	Date        string         `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Updated     string         `xml:"http://www.w3.org/2005/Atom updated"`
+	Link        string         `xml:"link"`
+	Categories  []string       `xml:"category"`
+	Subjects    []string       `xml:"http://purl.org/dc/elements/1.1/ subject"`
+	Enclosures  []RSSEnclosure `xml:"enclosure"`
+	iTunesModule
 }
 
-func (rfe RSSFeedEntry) toFeedEntry() (*FeedEntry, error) {
-	published, err := parseRSSDate(rfe.Published)
-	if err != nil {
-		return nil, err
+func (rfe RSSFeedEntry) toFeedEntry(channelBase string) *FeedEntry {
+	var enclosures []Enclosure
+	for _, enclosure := range rfe.Enclosures {
+		if enclosure.URL == "" {
+			continue
+		}
+		enclosures = append(enclosures, enclosure.toEnclosure())
 	}
+	rfe.iTunesModule.apply(enclosures)
 
-	return &FeedEntry{
-		Title:     strings.TrimSpace(rfe.Title),
-		Published: published,
-		Link:      strings.TrimSpace(rfe.Link),
-		Content:   strings.TrimSpace(rfe.Description),
-	}, nil
+	description := strings.TrimSpace(rfe.Description)
+	content := strings.TrimSpace(rfe.Encoded)
+	if content == "" {
+		content = description
+	}
 
+	fe := &FeedEntry{
+		ID:          strings.TrimSpace(rfe.GUID),
+		Title:       strings.TrimSpace(rfe.Title),
+		Description: description,
+		Published:   normalizeDate(rfe.Published, rfe.Date, rfe.Updated),
+		Updated:     normalizeDate(rfe.Updated, rfe.Date),
+		Link:        strings.TrimSpace(rfe.Link),
+		Content:     content,
+		Categories:  normalizeCategories(rfe.Categories, rfe.Subjects),
+		Enclosures:  enclosures,
+		base:        joinBase(channelBase, rfe.Base),
+	}
+	rfe.mediaModule.apply(fe)
+	return fe
 }
 
 type RSSFeed struct {
 	Channel struct {
-		Title         string         `xml:"title"`
-		Description   string         `xml:"description"`
-		LastBuildDate string         `xml:"lastBuildDate"`
-		Link          []string       `xml:"link"`
-		Items         []RSSFeedEntry `xml:"item"`
-		// TODO: find a way to parse both <atom:link> and <link>
-		AtomLink string `xml:"atom:link"`
+		Title         string `xml:"title"`
+		Description   string `xml:"description"`
+		LastBuildDate string `xml:"lastBuildDate"`
+		// AtomLinks must come before Link, which would otherwise match
+		// <atom:link> as well.
+		AtomLinks   []Link   `xml:"http://www.w3.org/2005/Atom link"`
+		Link        []string `xml:"link"`
+		TTL         string   `xml:"ttl"`
+		SkipHours   []string `xml:"skipHours>hour"`
+		SkipDays    []string `xml:"skipDays>day"`
+		Categories  []string `xml:"category"`
+		ITunesImage struct {
+			Href string `xml:"href,attr"`
+		} `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+		Image struct {
+			URL string `xml:"url"`
+		} `xml:"image"`
+		Items []RSSFeedEntry `xml:"item"`
+		xmlBase
+		syndicationModule
 	} `xml:"channel"`
 }
 
-func parseRSSDate(date string) (string, error) {
-	dateFormats := []string{
-		time.RFC1123,
-		time.RFC1123Z,
-	}
-	for _, format := range dateFormats {
-		t, err := time.Parse(format, date)
-		if err == nil {
-			return t.Format(time.RFC3339), nil
-		}
-	}
-	return "", fmt.Errorf("Unrecognized date format: %s", date)
-}
-
 func (rf RSSFeed) toFeed() *Feed {
 	var entries []FeedEntry
 	for _, entry := range rf.Channel.Items {
-		fe, err := entry.toFeedEntry()
-		if err != nil {
-			continue
+		entries = append(entries, *entry.toFeedEntry(rf.Channel.Base))
+	}
+	var siteURL string
+	if len(rf.Channel.Link) > 0 {
+		siteURL = rf.Channel.Link[0]
+	}
+	var hubURL, topicURL string
+	for _, link := range rf.Channel.AtomLinks {
+		switch link.Rel {
+		case "hub":
+			if hubURL == "" {
+				hubURL = strings.TrimSpace(link.Href)
+			}
+		case "self":
+			topicURL = strings.TrimSpace(link.Href)
 		}
-		entries = append(entries, *fe)
 	}
 	return &Feed{
-		Title:   rf.Channel.Title,
-		FeedURL: rf.Channel.AtomLink,
-		SiteURL: rf.Channel.Link[0],
-		Entries: entries,
-		Type:    RSS,
+		Title:    strings.TrimSpace(rf.Channel.Title),
+		SiteURL:  siteURL,
+		HubURL:   hubURL,
+		TopicURL: topicURL,
+		IconURL:  firstNonEmpty(rf.Channel.Image.URL, rf.Channel.ITunesImage.Href),
+		Entries:  entries,
+		Type:     RSS,
+		UpdateHints: UpdateHints{
+			TTL:          parseTTL(rf.Channel.TTL),
+			UpdatePeriod: rf.Channel.updatePeriod(),
+			SkipHours:    parseSkipHours(rf.Channel.SkipHours),
+			SkipDays:     parseSkipDays(rf.Channel.SkipDays),
+		},
+		categories: normalizeCategories(rf.Channel.Categories),
 	}
 }
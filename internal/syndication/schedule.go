@@ -0,0 +1,122 @@
+package syndication
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UpdateHints are the publisher's suggestions on how often a feed should be
+// polled. Zero values mean no suggestion was made.
+type UpdateHints struct {
+	TTL          time.Duration  `json:"ttl,omitempty"`
+	UpdatePeriod time.Duration  `json:"update_period,omitempty"`
+	SkipHours    []int          `json:"skip_hours,omitempty"`
+	SkipDays     []time.Weekday `json:"skip_days,omitempty"`
+}
+
+// IsZero reports whether no hints are set.
+func (h UpdateHints) IsZero() bool {
+	return h.TTL == 0 && h.UpdatePeriod == 0 && len(h.SkipHours) == 0 && len(h.SkipDays) == 0
+}
+
+// Value stores the hints as JSON, or as an empty string when none are set.
+func (h UpdateHints) Value() (driver.Value, error) {
+	if h.IsZero() {
+		return "", nil
+	}
+	b, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan reads hints stored by Value.
+func (h *UpdateHints) Scan(src any) error {
+	var b []byte
+	switch v := src.(type) {
+	case nil:
+	case string:
+		b = []byte(v)
+	case []byte:
+		b = v
+	default:
+		return fmt.Errorf("Cannot scan %T into UpdateHints", src)
+	}
+	*h = UpdateHints{}
+	if len(bytes.TrimSpace(b)) == 0 {
+		return nil
+	}
+	return json.Unmarshal(b, h)
+}
+
+// syndicationModule holds the elements of the RSS 1.0 syndication module
+// (sy:updatePeriod and sy:updateFrequency), which also appear in RSS 2.0
+// and Atom feeds.
+type syndicationModule struct {
+	UpdatePeriod    string `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+	UpdateFrequency string `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+}
+
+func (sm syndicationModule) updatePeriod() time.Duration {
+	var period time.Duration
+	switch strings.ToLower(strings.TrimSpace(sm.UpdatePeriod)) {
+	case "hourly":
+		period = time.Hour
+	case "daily":
+		period = 24 * time.Hour
+	case "weekly":
+		period = 7 * 24 * time.Hour
+	case "monthly":
+		period = 30 * 24 * time.Hour
+	case "yearly":
+		period = 365 * 24 * time.Hour
+	default:
+		return 0
+	}
+
+	frequency, err := strconv.Atoi(strings.TrimSpace(sm.UpdateFrequency))
+	if err != nil || frequency < 1 {
+		frequency = 1
+	}
+	return period / time.Duration(frequency)
+}
+
+func parseTTL(ttl string) time.Duration {
+	minutes, err := strconv.Atoi(strings.TrimSpace(ttl))
+	if err != nil || minutes < 0 {
+		return 0
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+func parseSkipHours(hours []string) []int {
+	var skip []int
+	for _, hour := range hours {
+		h, err := strconv.Atoi(strings.TrimSpace(hour))
+		if err != nil || h < 0 || h > 24 {
+			continue
+		}
+		// Some publishers count hours from 1 to 24.
+		skip = append(skip, h%24)
+	}
+	return skip
+}
+
+func parseSkipDays(days []string) []time.Weekday {
+	var skip []time.Weekday
+	for _, day := range days {
+		for wd := time.Sunday; wd <= time.Saturday; wd++ {
+			if strings.EqualFold(strings.TrimSpace(day), wd.String()) {
+				skip = append(skip, wd)
+				break
+			}
+		}
+	}
+	return skip
+}
@@ -0,0 +1,218 @@
+package syndication
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var ErrInvalidScraperRules = errors.New("Invalid scraper rules")
+
+// ScraperRules are the CSS selectors that turn an HTML page into a feed. Item
+// selects the element of each entry on the page. The other selectors are
+// applied within an item. Title defaults to the item's text, Link to the
+// first link in it, and Date and Content are optional.
+type ScraperRules struct {
+	Item    string `json:"item"`
+	Title   string `json:"title,omitempty"`
+	Link    string `json:"link,omitempty"`
+	Date    string `json:"date,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+// compiledRules are ScraperRules with their selectors parsed. A nil selector
+// means the rule was left empty.
+type compiledRules struct {
+	item, title, link, date, content selector
+}
+
+// IsZero reports whether no rules are set.
+func (r ScraperRules) IsZero() bool {
+	return r == ScraperRules{}
+}
+
+// Validate reports whether every selector of the rules is valid and an item
+// selector is set.
+func (r ScraperRules) Validate() error {
+	_, err := r.compile()
+	return err
+}
+
+func (r ScraperRules) compile() (compiledRules, error) {
+	var compiled compiledRules
+	if strings.TrimSpace(r.Item) == "" {
+		return compiled, fmt.Errorf("%w: an item selector is required", ErrInvalidScraperRules)
+	}
+	for _, rule := range []struct {
+		source string
+		target *selector
+	}{
+		{r.Item, &compiled.item},
+		{r.Title, &compiled.title},
+		{r.Link, &compiled.link},
+		{r.Date, &compiled.date},
+		{r.Content, &compiled.content},
+	} {
+		if strings.TrimSpace(rule.source) == "" {
+			continue
+		}
+		sel, err := compileSelector(rule.source)
+		if err != nil {
+			return compiled, fmt.Errorf("%w: %w", ErrInvalidScraperRules, err)
+		}
+		*rule.target = sel
+	}
+	return compiled, nil
+}
+
+// Value stores the rules as JSON, or as an empty string when none are set.
+func (r ScraperRules) Value() (driver.Value, error) {
+	if r.IsZero() {
+		return "", nil
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan reads rules stored by Value.
+func (r *ScraperRules) Scan(src any) error {
+	var b []byte
+	switch v := src.(type) {
+	case nil:
+	case string:
+		b = []byte(v)
+	case []byte:
+		b = v
+	default:
+		return fmt.Errorf("Cannot scan %T into ScraperRules", src)
+	}
+	*r = ScraperRules{}
+	if len(bytes.TrimSpace(b)) == 0 {
+		return nil
+	}
+	return json.Unmarshal(b, r)
+}
+
+// Scrape fetches the page at pageURL and builds a feed from it with rules.
+// Like GetNewEntries, it makes the request conditional on cv and returns
+// a *NotModifiedError when the page has not changed.
+func (f *Fetcher) Scrape(ctx context.Context, pageURL string, rules ScraperRules, cv CacheValidators) (*Feed, error) {
+	compiled, err := rules.compile()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := f.fetchDocument(ctx, pageURL, cv)
+	if err != nil {
+		return nil, err
+	}
+	if resp.movedTo != "" {
+		pageURL = resp.movedTo
+	}
+
+	feed, err := scrapeFeed(resp.body, pageURL, compiled)
+	if err != nil {
+		return nil, err
+	}
+	feed.CacheValidators = cacheValidatorsFromResponse(resp)
+	feed.MovedTo = resp.movedTo
+	return feed, nil
+}
+
+// scrapeFeed builds a feed from an HTML page. pageURL is the address the
+// page was fetched from and becomes the feed's address.
+func scrapeFeed(page []byte, pageURL string, rules compiledRules) (*Feed, error) {
+	doc, err := html.Parse(bytes.NewReader(page))
+	if err != nil {
+		return nil, err
+	}
+
+	feed := &Feed{
+		Type:    Scraper,
+		FeedURL: pageURL,
+		SiteURL: pageURL,
+	}
+	if title := findElement(doc, atom.Title); title != nil {
+		feed.Title = strings.Join(strings.Fields(textContent(title)), " ")
+	}
+
+	for _, item := range rules.item.selectAll(doc) {
+		entry, ok := scrapeEntry(item, rules)
+		if ok {
+			// Relative references are relative to the page, wherever the
+			// entry links to.
+			entry.base = pageURL
+			feed.Entries = append(feed.Entries, entry)
+		}
+	}
+
+	feed.resolveURLs(pageURL)
+	if feed.Title == "" {
+		feed.Title = hostname(pageURL)
+	}
+	return feed, nil
+}
+
+// scrapeEntry applies the rules within a single item. It reports false when
+// the item has neither a title nor a link.
+func scrapeEntry(item *html.Node, rules compiledRules) (FeedEntry, bool) {
+	var entry FeedEntry
+
+	titleNode := item
+	if rules.title != nil {
+		titleNode = rules.title.selectFirst(item)
+	}
+	if titleNode != nil {
+		entry.Title = strings.Join(strings.Fields(textContent(titleNode)), " ")
+	}
+
+	var linkNode *html.Node
+	if rules.link != nil {
+		linkNode = rules.link.selectFirst(item)
+	} else if item.DataAtom == atom.A {
+		linkNode = item
+	}
+	if linkNode != nil && attrValue(linkNode, "href") == "" {
+		linkNode = findElement(linkNode, atom.A)
+	}
+	if linkNode == nil && rules.link == nil {
+		linkNode = findElement(item, atom.A)
+	}
+	if linkNode != nil {
+		entry.Link = strings.TrimSpace(attrValue(linkNode, "href"))
+	}
+
+	if rules.date != nil {
+		if dateNode := rules.date.selectFirst(item); dateNode != nil {
+			entry.Published = normalizeDate(
+				attrValue(dateNode, "datetime"),
+				attrValue(dateNode, "content"),
+				strings.Join(strings.Fields(textContent(dateNode)), " "),
+			)
+		}
+	}
+
+	if rules.content != nil {
+		var b strings.Builder
+		for _, n := range rules.content.selectAll(item) {
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				if err := html.Render(&b, c); err != nil {
+					break
+				}
+			}
+		}
+		entry.Content = strings.TrimSpace(b.String())
+	}
+
+	return entry, entry.Title != "" || entry.Link != ""
+}
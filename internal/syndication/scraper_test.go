@@ -0,0 +1,125 @@
+package syndication
+
+import (
+	"errors"
+	"testing"
+)
+
+const scraperFixture = `<!DOCTYPE html>
+<html><head><title> Example
+  News </title></head><body>
+<article class="story">
+  <h2><a href="/news/1">First story</a></h2>
+  <time datetime="2025-06-01T10:00:00Z">June 1</time>
+  <div class="body"><p>Hello <img src="img/1.png"></p></div>
+</article>
+<article class="story">
+  <h2>Second   story</h2>
+  <a class="more" href="news/2?ref=home">Read more</a>
+</article>
+<article class="story"><span></span></article>
+<a class="story" href="https://elsewhere.example/3">Third story</a>
+</body></html>`
+
+func TestScrapeFeed(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules ScraperRules
+		want  []FeedEntry
+	}{
+		{
+			name:  "defaults",
+			rules: ScraperRules{Item: ".story"},
+			want: []FeedEntry{
+				{Title: "First story June 1 Hello", Link: "https://news.example/news/1"},
+				{Title: "Second story Read more", Link: "https://news.example/section/news/2?ref=home"},
+				{Title: "Third story", Link: "https://elsewhere.example/3"},
+			},
+		},
+		{
+			name: "selectors",
+			rules: ScraperRules{
+				Item:    "article.story",
+				Title:   "h2",
+				Link:    "h2 a, a.more",
+				Date:    "time",
+				Content: ".body",
+			},
+			want: []FeedEntry{
+				{
+					Title:     "First story",
+					Link:      "https://news.example/news/1",
+					Published: "2025-06-01T10:00:00Z",
+					Content:   `<p>Hello <img src="https://news.example/section/img/1.png"/></p>`,
+				},
+				{Title: "Second story", Link: "https://news.example/section/news/2?ref=home"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := tt.rules.compile()
+			if err != nil {
+				t.Fatal(err)
+			}
+			feed, err := scrapeFeed([]byte(scraperFixture), "https://news.example/section/", rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if feed.Title != "Example News" {
+				t.Errorf("title = %q, want %q", feed.Title, "Example News")
+			}
+			if feed.FeedURL != "https://news.example/section/" || feed.Type != Scraper {
+				t.Errorf("feed URL = %q, type = %v", feed.FeedURL, feed.Type)
+			}
+			if len(feed.Entries) != len(tt.want) {
+				t.Fatalf("got %d entries, want %d", len(feed.Entries), len(tt.want))
+			}
+			for i, want := range tt.want {
+				got := feed.Entries[i]
+				if got.Title != want.Title || got.Link != want.Link || got.Published != want.Published || got.Content != want.Content {
+					t.Errorf("entry %d = %q %q %q %q, want %q %q %q %q", i,
+						got.Title, got.Link, got.Published, got.Content,
+						want.Title, want.Link, want.Published, want.Content)
+				}
+			}
+		})
+	}
+}
+
+func TestScrapeFeedUntitledPage(t *testing.T) {
+	rules, err := ScraperRules{Item: "a"}.compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err := scrapeFeed([]byte(`<a href="/1">One</a>`), "https://www.news.example/", rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.Title != hostname("https://www.news.example/") {
+		t.Errorf("title = %q, want the host name", feed.Title)
+	}
+}
+
+func TestScraperRulesValidate(t *testing.T) {
+	tests := []struct {
+		rules ScraperRules
+		valid bool
+	}{
+		{ScraperRules{Item: "li"}, true},
+		{ScraperRules{Item: "li", Title: "h2", Link: "a[href]", Date: "time", Content: ".body"}, true},
+		{ScraperRules{}, false},
+		{ScraperRules{Item: " ", Title: "h2"}, false},
+		{ScraperRules{Item: "li", Title: "h2 >"}, false},
+		{ScraperRules{Item: "li:hover"}, false},
+	}
+	for _, tt := range tests {
+		err := tt.rules.Validate()
+		if tt.valid && err != nil {
+			t.Errorf("%+v: %v", tt.rules, err)
+		} else if !tt.valid && !errors.Is(err, ErrInvalidScraperRules) {
+			t.Errorf("%+v: got %v, want ErrInvalidScraperRules", tt.rules, err)
+		}
+	}
+}
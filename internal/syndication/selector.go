@@ -0,0 +1,415 @@
+package syndication
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// selector is a compiled CSS selector list. It supports type, class, id and
+// attribute selectors, the :first-child and :last-child pseudo-classes and
+// the descendant, child and sibling combinators, which covers what is needed
+// to pick items out of a page.
+type selector []complexSelector
+
+// complexSelector is a chain of compound selectors, stored from the
+// rightmost, which is matched against the element itself, leftwards.
+type complexSelector []selectorStep
+
+// selectorStep is a compound selector and the combinator that relates it to
+// the step after it.
+type selectorStep struct {
+	compound   compoundSelector
+	combinator byte
+}
+
+type compoundSelector struct {
+	tag        string
+	id         string
+	classes    []string
+	attrs      []attrSelector
+	firstChild bool
+	lastChild  bool
+}
+
+type attrSelector struct {
+	key   string
+	op    string
+	value string
+}
+
+// compileSelector parses a selector list such as "div.post > h2 a, li".
+func compileSelector(s string) (selector, error) {
+	var sel selector
+	for _, part := range splitSelectorList(s) {
+		complex, err := compileComplexSelector(part)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid selector %q: %w", s, err)
+		}
+		sel = append(sel, complex)
+	}
+	return sel, nil
+}
+
+// splitSelectorList splits s on the commas that are not inside an attribute
+// selector. Empty parts are kept, so that the list can be rejected.
+func splitSelectorList(s string) []string {
+	var parts []string
+	var depth int
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case c == ',' && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	parts = append(parts, s[start:])
+	for i, part := range parts {
+		parts[i] = strings.TrimSpace(part)
+	}
+	return parts
+}
+
+func compileComplexSelector(s string) (complexSelector, error) {
+	var steps []selectorStep
+	combinator := byte(0)
+	i := 0
+	for i < len(s) {
+		sawSpace := false
+		for i < len(s) && isSelectorSpace(s[i]) {
+			sawSpace = true
+			i++
+		}
+		if i == len(s) {
+			break
+		}
+		if c := s[i]; c == '>' || c == '+' || c == '~' {
+			if len(steps) == 0 || combinator != 0 {
+				return nil, fmt.Errorf("unexpected %q", c)
+			}
+			combinator = c
+			i++
+			continue
+		}
+		if len(steps) > 0 && combinator == 0 {
+			if !sawSpace {
+				return nil, fmt.Errorf("unexpected %q", s[i])
+			}
+			combinator = ' '
+		}
+		if len(steps) > 0 {
+			steps[len(steps)-1].combinator = combinator
+		}
+		compound, n, err := compileCompoundSelector(s[i:])
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, selectorStep{compound: compound})
+		combinator = 0
+		i += n
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("empty")
+	}
+	if combinator != 0 {
+		return nil, fmt.Errorf("dangling %q", combinator)
+	}
+
+	// Reverse so that matching starts from the subject element.
+	complex := make(complexSelector, len(steps))
+	for i, step := range steps {
+		complex[len(steps)-1-i] = step
+	}
+	return complex, nil
+}
+
+// compileCompoundSelector parses the compound selector at the start of s and
+// returns it along with the number of bytes it spans.
+func compileCompoundSelector(s string) (compoundSelector, int, error) {
+	var compound compoundSelector
+	i := 0
+	if i < len(s) && s[i] == '*' {
+		i++
+	} else if n := identLength(s[i:]); n > 0 {
+		compound.tag = strings.ToLower(s[i : i+n])
+		i += n
+	}
+
+	for i < len(s) {
+		switch s[i] {
+		case '#', '.':
+			n := identLength(s[i+1:])
+			if n == 0 {
+				return compound, 0, fmt.Errorf("missing name after %q", s[i])
+			}
+			if s[i] == '#' {
+				compound.id = s[i+1 : i+1+n]
+			} else {
+				compound.classes = append(compound.classes, s[i+1:i+1+n])
+			}
+			i += 1 + n
+		case '[':
+			end := attrSelectorEnd(s[i:])
+			if end < 0 {
+				return compound, 0, fmt.Errorf("unterminated attribute selector")
+			}
+			attr, err := compileAttrSelector(s[i+1 : i+end])
+			if err != nil {
+				return compound, 0, err
+			}
+			compound.attrs = append(compound.attrs, attr)
+			i += end + 1
+		case ':':
+			n := identLength(s[i+1:])
+			switch strings.ToLower(s[i+1 : i+1+n]) {
+			case "first-child":
+				compound.firstChild = true
+			case "last-child":
+				compound.lastChild = true
+			default:
+				return compound, 0, fmt.Errorf("unsupported pseudo-class %q", s[i:i+1+n])
+			}
+			i += 1 + n
+		default:
+			if i == 0 {
+				return compound, 0, fmt.Errorf("unexpected %q", s[i])
+			}
+			return compound, i, nil
+		}
+	}
+	return compound, i, nil
+}
+
+func compileAttrSelector(s string) (attrSelector, error) {
+	s = strings.TrimSpace(s)
+	n := identLength(s)
+	if n == 0 {
+		return attrSelector{}, fmt.Errorf("missing attribute name")
+	}
+	attr := attrSelector{key: strings.ToLower(s[:n])}
+	rest := strings.TrimSpace(s[n:])
+	if rest == "" {
+		return attr, nil
+	}
+	for _, op := range []string{"~=", "^=", "$=", "*=", "|=", "="} {
+		if strings.HasPrefix(rest, op) {
+			attr.op = op
+			attr.value = strings.TrimSpace(rest[len(op):])
+			if n := len(attr.value); n >= 2 && (attr.value[0] == '"' || attr.value[0] == '\'') && attr.value[n-1] == attr.value[0] {
+				attr.value = attr.value[1 : n-1]
+			}
+			return attr, nil
+		}
+	}
+	return attrSelector{}, fmt.Errorf("invalid attribute selector [%s]", s)
+}
+
+// attrSelectorEnd returns the index of the "]" that closes the attribute
+// selector at the start of s, skipping quoted values, or -1.
+func attrSelectorEnd(s string) int {
+	var quote byte
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ']':
+			return i
+		}
+	}
+	return -1
+}
+
+func identLength(s string) int {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c == '-' || c == '_' || c >= 0x80 ||
+			(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')) {
+			return i
+		}
+	}
+	return len(s)
+}
+
+func isSelectorSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// selectAll returns the descendants of root that match sel, in document
+// order.
+func (sel selector) selectAll(root *html.Node) []*html.Node {
+	var matches []*html.Node
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			if sel.match(c) {
+				matches = append(matches, c)
+			}
+			walk(c)
+		}
+	}
+	walk(root)
+	return matches
+}
+
+// selectFirst returns the first descendant of root that matches sel.
+func (sel selector) selectFirst(root *html.Node) *html.Node {
+	var walk func(*html.Node) *html.Node
+	walk = func(n *html.Node) *html.Node {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			if sel.match(c) {
+				return c
+			}
+			if found := walk(c); found != nil {
+				return found
+			}
+		}
+		return nil
+	}
+	return walk(root)
+}
+
+func (sel selector) match(n *html.Node) bool {
+	for _, complex := range sel {
+		if complex.match(n, 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// match reports whether n matches the steps of the selector from i on.
+func (complex complexSelector) match(n *html.Node, i int) bool {
+	if !complex[i].compound.match(n) {
+		return false
+	}
+	if i == len(complex)-1 {
+		return true
+	}
+	switch complex[i+1].combinator {
+	case '>':
+		parent := n.Parent
+		return parent != nil && parent.Type == html.ElementNode && complex.match(parent, i+1)
+	case '+':
+		sibling := previousElement(n)
+		return sibling != nil && complex.match(sibling, i+1)
+	case '~':
+		for sibling := previousElement(n); sibling != nil; sibling = previousElement(sibling) {
+			if complex.match(sibling, i+1) {
+				return true
+			}
+		}
+	default:
+		for parent := n.Parent; parent != nil && parent.Type == html.ElementNode; parent = parent.Parent {
+			if complex.match(parent, i+1) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (compound compoundSelector) match(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	if compound.tag != "" && n.Data != compound.tag {
+		return false
+	}
+	if compound.id != "" && attrValue(n, "id") != compound.id {
+		return false
+	}
+	if len(compound.classes) > 0 {
+		classes := strings.Fields(attrValue(n, "class"))
+		for _, class := range compound.classes {
+			if !slices.Contains(classes, class) {
+				return false
+			}
+		}
+	}
+	for _, attr := range compound.attrs {
+		if !attr.match(n) {
+			return false
+		}
+	}
+	if compound.firstChild && previousElement(n) != nil {
+		return false
+	}
+	if compound.lastChild && nextElement(n) != nil {
+		return false
+	}
+	return true
+}
+
+func (attr attrSelector) match(n *html.Node) bool {
+	var value string
+	found := false
+	for _, a := range n.Attr {
+		if a.Key == attr.key {
+			value, found = a.Val, true
+			break
+		}
+	}
+	if !found {
+		return false
+	}
+	switch attr.op {
+	case "":
+		return true
+	case "=":
+		return value == attr.value
+	case "~=":
+		return slices.Contains(strings.Fields(value), attr.value)
+	case "^=":
+		return attr.value != "" && strings.HasPrefix(value, attr.value)
+	case "$=":
+		return attr.value != "" && strings.HasSuffix(value, attr.value)
+	case "*=":
+		return attr.value != "" && strings.Contains(value, attr.value)
+	case "|=":
+		return value == attr.value || strings.HasPrefix(value, attr.value+"-")
+	}
+	return false
+}
+
+func previousElement(n *html.Node) *html.Node {
+	for s := n.PrevSibling; s != nil; s = s.PrevSibling {
+		if s.Type == html.ElementNode {
+			return s
+		}
+	}
+	return nil
+}
+
+func nextElement(n *html.Node) *html.Node {
+	for s := n.NextSibling; s != nil; s = s.NextSibling {
+		if s.Type == html.ElementNode {
+			return s
+		}
+	}
+	return nil
+}
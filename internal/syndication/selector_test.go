@@ -0,0 +1,132 @@
+package syndication
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestCompileSelector(t *testing.T) {
+	valid := []string{
+		"a",
+		"*",
+		"div.post > h2 a",
+		"li + li",
+		"h2 ~ p",
+		"#main .entry",
+		"a.b.c#d",
+		"a[href]",
+		`a[href^="https:"]`,
+		`a[title="x, y"], li`,
+		`a[title='a]b']`,
+		"li:first-child, li:LAST-CHILD",
+		"  ul\n>\tli  ",
+		"A[HREF]",
+	}
+	for _, s := range valid {
+		_, err := compileSelector(s)
+		if err != nil {
+			t.Errorf("compileSelector(%q): %v", s, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"  ",
+		"a >",
+		"> a",
+		"a > > b",
+		"a,",
+		"a,,b",
+		",a",
+		"[x",
+		`[x="]`,
+		"[=x]",
+		"[x!=y]",
+		":hover",
+		"a:nth-child(2)",
+		"a.",
+		"#",
+		"a)",
+		"a..b",
+	}
+	for _, s := range invalid {
+		_, err := compileSelector(s)
+		if err == nil {
+			t.Errorf("compileSelector(%q) succeeded, want an error", s)
+		}
+	}
+}
+
+const selectorFixture = `<!DOCTYPE html>
+<html><body>
+<div id="main" class="content wide">
+  <h1>Posts</h1>
+  <ul>
+    <li class="post"><a href="/1" title="first, post" lang="en-GB">One</a></li>
+    <li class="post featured"><a href="https://other.example/2" rel="nofollow external">Two</a></li>
+    <li class="post"><a href="/3">Three</a></li>
+  </ul>
+  <p class="note">Note</p>
+</div>
+<div class="sidebar"><a href="/about">About</a></div>
+</body></html>`
+
+func TestSelectorSelectAll(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(selectorFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		selector string
+		want     string
+	}{
+		{"a", "One Two Three About"},
+		{"#main a", "One Two Three"},
+		{"div > a", "About"},
+		{"ul > a", ""},
+		{"li.featured a", "Two"},
+		{".post.featured", "Two"},
+		{"li:first-child", "One"},
+		{"li:last-child a", "Three"},
+		{"li + li", "Two Three"},
+		{"h1 ~ p", "Note"},
+		{"h1 + p", ""},
+		{`a[href^="https:"]`, "Two"},
+		{`a[href$="3"]`, "Three"},
+		{`a[href*="other"]`, "Two"},
+		{`a[rel~="external"]`, "Two"},
+		{`a[rel~="ext"]`, ""},
+		{`a[lang|="en"]`, "One"},
+		{`a[title="first, post"]`, "One"},
+		{`a[title]`, "One"},
+		{`a[href^=""]`, ""},
+		{".sidebar a, h1", "Posts About"},
+		{"div.content.wide ul li.post a", "One Two Three"},
+		{"DIV#main > H1", "Posts"},
+	}
+	for _, tt := range tests {
+		sel, err := compileSelector(tt.selector)
+		if err != nil {
+			t.Errorf("%s: %v", tt.selector, err)
+			continue
+		}
+		var got []string
+		for _, n := range sel.selectAll(doc) {
+			got = append(got, strings.TrimSpace(textContent(n)))
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.selector, strings.Join(got, " "), tt.want)
+		}
+	}
+
+	sel, err := compileSelector("li a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first := sel.selectFirst(doc); first == nil || textContent(first) != "One" {
+		t.Errorf("selectFirst returned %v, want the first link", first)
+	}
+}
@@ -0,0 +1,191 @@
+package syndication
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// xmlBase is the xml:base attribute, which sets the base URL for relative
+// references within an element.
+type xmlBase struct {
+	Base string `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+}
+
+// joinBase resolves each xml:base against the ones before it, from the
+// outermost element in.
+func joinBase(bases ...string) string {
+	var joined string
+	for _, base := range bases {
+		if strings.TrimSpace(base) != "" {
+			joined = resolveURL(joined, base)
+		}
+	}
+	return joined
+}
+
+// resolveURL resolves ref against base. ref is returned unchanged when
+// either cannot be parsed.
+func resolveURL(base, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if base == "" || ref == "" {
+		return ref
+	}
+	baseURL, err := url.Parse(strings.TrimSpace(base))
+	if err != nil {
+		return ref
+	}
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return baseURL.ResolveReference(refURL).String()
+}
+
+// hostname returns the host of rawURL without a leading "www.", or rawURL
+// itself when it has none.
+func hostname(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return rawURL
+	}
+	return strings.TrimPrefix(u.Hostname(), "www.")
+}
+
+// resolveURLs makes the links of the feed and its entries absolute. feedURL
+// is the address the document was fetched from. Relative references in an
+// entry resolve against its xml:base, then its link, then the feed's site.
+func (f *Feed) resolveURLs(feedURL string) {
+	docBase := resolveURL(feedURL, f.base)
+	if docBase == "" {
+		docBase = feedURL
+	}
+	f.FeedURL = resolveURL(docBase, f.FeedURL)
+	f.SiteURL = resolveURL(docBase, f.SiteURL)
+	f.IconURL = resolveURL(docBase, f.IconURL)
+	f.HubURL = resolveURL(docBase, f.HubURL)
+	f.TopicURL = resolveURL(docBase, f.TopicURL)
+
+	for i := range f.Entries {
+		fe := &f.Entries[i]
+
+		var base string
+		if fe.base != "" {
+			base = resolveURL(docBase, fe.base)
+		}
+		linkBase := base
+		if linkBase == "" {
+			linkBase = firstNonEmpty(f.SiteURL, docBase)
+		}
+		fe.Link = resolveURL(linkBase, fe.Link)
+		if base == "" {
+			base = firstNonEmpty(fe.Link, f.SiteURL, docBase)
+		}
+
+		fe.Content = resolveContentURLs(fe.Content, base)
+		fe.Description = resolveContentURLs(fe.Description, base)
+		fe.Media.ThumbnailURL = resolveURL(base, fe.Media.ThumbnailURL)
+		for j := range fe.Enclosures {
+			fe.Enclosures[j].URL = resolveURL(base, fe.Enclosures[j].URL)
+			fe.Enclosures[j].ImageURL = resolveURL(base, fe.Enclosures[j].ImageURL)
+		}
+	}
+}
+
+// resolveContentURLs rewrites the relative href, src and srcset attributes
+// of an HTML fragment against base. The fragment is returned unchanged when
+// it has nothing to rewrite.
+func resolveContentURLs(content, base string) string {
+	if base == "" || !strings.Contains(content, "<") {
+		return content
+	}
+	baseURL, err := url.Parse(base)
+	if err != nil || !baseURL.IsAbs() {
+		return content
+	}
+
+	context := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(content), context)
+	if err != nil {
+		return content
+	}
+
+	changed := false
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			for i, attr := range n.Attr {
+				var value string
+				switch attr.Key {
+				case "href", "src":
+					value = resolveReference(baseURL, attr.Val)
+				case "srcset":
+					value = resolveSrcset(baseURL, attr.Val)
+				default:
+					continue
+				}
+				if value != attr.Val {
+					n.Attr[i].Val = value
+					changed = true
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	for _, n := range nodes {
+		walk(n)
+	}
+	if !changed {
+		return content
+	}
+
+	var b strings.Builder
+	for _, n := range nodes {
+		err = html.Render(&b, n)
+		if err != nil {
+			return content
+		}
+	}
+	return b.String()
+}
+
+// resolveReference resolves a relative reference against base. Absolute
+// URLs, such as mailto: and data: links, and fragments pointing within the
+// entry are left alone.
+func resolveReference(base *url.URL, ref string) string {
+	trimmed := strings.TrimSpace(ref)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return ref
+	}
+	u, err := url.Parse(trimmed)
+	if err != nil || u.IsAbs() {
+		return ref
+	}
+	return base.ResolveReference(u).String()
+}
+
+// resolveSrcset resolves the URL of each image candidate in a srcset.
+func resolveSrcset(base *url.URL, srcset string) string {
+	changed := false
+	candidates := strings.Split(srcset, ",")
+	for i, candidate := range candidates {
+		fields := strings.Fields(candidate)
+		if len(fields) == 0 {
+			continue
+		}
+		resolved := resolveReference(base, fields[0])
+		if resolved != fields[0] {
+			fields[0] = resolved
+			changed = true
+		}
+		candidates[i] = strings.Join(fields, " ")
+	}
+	if !changed {
+		return srcset
+	}
+	return strings.Join(candidates, ", ")
+}
@@ -0,0 +1,187 @@
+package syndication
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HubRequest is a subscription request sent to a WebSub hub. The hub checks
+// it by calling CallbackURL, and signs the content it pushes there with
+// Secret. Lease is the subscription duration asked for; the hub has the final
+// say and reports it when verifying the request.
+type HubRequest struct {
+	HubURL      string
+	TopicURL    string
+	CallbackURL string
+	Secret      string
+	Lease       time.Duration
+}
+
+// Subscribe asks the hub to push updates of the topic to the callback. The
+// hub accepting the request does not mean the subscription is active: that is
+// only the case once it has verified the intent with the callback.
+func (f *Fetcher) Subscribe(ctx context.Context, hr HubRequest) error {
+	return f.hubRequest(ctx, "subscribe", hr)
+}
+
+// Unsubscribe asks the hub to stop pushing updates of the topic to the
+// callback.
+func (f *Fetcher) Unsubscribe(ctx context.Context, hr HubRequest) error {
+	return f.hubRequest(ctx, "unsubscribe", hr)
+}
+
+func (f *Fetcher) hubRequest(ctx context.Context, mode string, hr HubRequest) error {
+	form := url.Values{}
+	form.Set("hub.mode", mode)
+	form.Set("hub.topic", hr.TopicURL)
+	form.Set("hub.callback", hr.CallbackURL)
+	if hr.Secret != "" && mode == "subscribe" {
+		form.Set("hub.secret", hr.Secret)
+	}
+	if hr.Lease > 0 && mode == "subscribe" {
+		form.Set("hub.lease_seconds", strconv.FormatInt(int64(hr.Lease/time.Second), 10))
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := f.send(ctx, http.MethodPost, hr.HubURL, header, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		reason := strings.TrimSpace(string(resp.body))
+		if len(reason) > 200 {
+			reason = reason[:200]
+		}
+		return fmt.Errorf("Hub refused %s request with status %d: %s", mode, resp.StatusCode, reason)
+	}
+	return nil
+}
+
+// VerifySignature reports whether signature, the value of the
+// X-Hub-Signature header of a pushed request, is a valid HMAC of body with
+// secret. sha1, sha256, sha384 and sha512 signatures are accepted.
+func VerifySignature(secret, signature string, body []byte) bool {
+	method, sum, ok := strings.Cut(strings.TrimSpace(signature), "=")
+	if !ok {
+		return false
+	}
+	var h func() hash.Hash
+	switch strings.ToLower(method) {
+	case "sha1":
+		h = sha1.New
+	case "sha256":
+		h = sha256.New
+	case "sha384":
+		h = sha512.New384
+	case "sha512":
+		h = sha512.New
+	default:
+		return false
+	}
+	expected, err := hex.DecodeString(sum)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(h, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// ParseFeed decodes a feed document that was not fetched by the Fetcher, such
+// as the content a hub pushes. feedURL is the feed's address and contentType
+// the Content-Type the document came with, if any. The type is detected from
+// the document when ft is empty.
+func ParseFeed(data []byte, ft FeedType, feedURL, contentType string) (*Feed, error) {
+	charset := contentTypeCharset(contentType)
+	if ft == "" {
+		return parseFeed(data, feedURL, charset)
+	}
+	return decodeFeed(data, ft, feedURL, charset)
+}
+
+// applyLinkHeaders sets the hub and topic of a feed from the Link headers of
+// the response it was fetched with. Publishers may advertise them there
+// instead of, or in addition to, the document, and the headers take
+// precedence.
+func applyLinkHeaders(feed *Feed, resp *response) {
+	base := resp.Request.URL.String()
+	for _, value := range resp.Header.Values("Link") {
+		for _, link := range parseLinkHeader(value) {
+			switch {
+			case link.hasRel("hub") && link.url != "":
+				feed.HubURL = resolveURL(base, link.url)
+			case link.hasRel("self") && link.url != "":
+				feed.TopicURL = resolveURL(base, link.url)
+			}
+		}
+	}
+}
+
+// headerLink is a single link of a Link header.
+type headerLink struct {
+	url string
+	rel []string
+}
+
+func (l headerLink) hasRel(rel string) bool {
+	for _, r := range l.rel {
+		if strings.EqualFold(r, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseLinkHeader parses a Link header value such as
+// `<https://hub.example/>; rel="hub", <https://example.com/feed>; rel=self`.
+// Only the target and the rel parameter of each link are kept.
+func parseLinkHeader(value string) []headerLink {
+	var links []headerLink
+	for {
+		start := strings.IndexByte(value, '<')
+		if start < 0 {
+			return links
+		}
+		end := strings.IndexByte(value[start:], '>')
+		if end < 0 {
+			return links
+		}
+		link := headerLink{url: strings.TrimSpace(value[start+1 : start+end])}
+		value = value[start+end+1:]
+
+		// The parameters run up to the next link. Quoted values may contain
+		// commas, so the separator is searched for outside of quotes.
+		params, rest := value, ""
+		inQuotes := false
+		for i, c := range value {
+			if c == '"' {
+				inQuotes = !inQuotes
+			} else if c == ',' && !inQuotes {
+				params, rest = value[:i], value[i+1:]
+				break
+			}
+		}
+		value = rest
+
+		for _, param := range strings.Split(params, ";") {
+			key, val, ok := strings.Cut(param, "=")
+			if !ok || !strings.EqualFold(strings.TrimSpace(key), "rel") {
+				continue
+			}
+			link.rel = strings.Fields(strings.Trim(strings.TrimSpace(val), `"`))
+		}
+		links = append(links, link)
+	}
+}
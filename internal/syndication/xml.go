@@ -0,0 +1,118 @@
+package syndication
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"mime"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	htmlcharset "golang.org/x/net/html/charset"
+)
+
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// xmlEncodingPattern matches the encoding of an XML declaration.
+var xmlEncodingPattern = regexp.MustCompile(`^<\?xml[^>]*?\sencoding\s*=\s*["']([^"']*)["']`)
+
+// newXMLDecoder returns a decoder that tolerates the mistakes commonly found
+// in feeds: HTML named entities, unescaped ampersands, stray control
+// characters, a leading byte order mark and legacy encodings. charset is the
+// one given by the Content-Type the document was served with, if any. As RFC
+// 7303 has it, it takes precedence over the XML declaration, unless it is
+// unknown or claims UTF-8 for a document that is not. Documents whose
+// encoding is missing, unknown or wrongly given as UTF-8 are read as UTF-8
+// when they are valid UTF-8 and as windows-1252 otherwise, which garbles
+// their non-ASCII text at worst.
+func newXMLDecoder(data []byte, charset string) *xml.Decoder {
+	data = cleanXML(data)
+	usable := func(label string) bool {
+		return knownCharset(label) && (!isUTF8Charset(label) || utf8.Valid(data))
+	}
+	label := charset
+	if !usable(label) {
+		label = declaredCharset(data)
+	}
+	if !usable(label) {
+		label = "utf-8"
+		if !utf8.Valid(data) {
+			label = "windows-1252"
+		}
+	}
+
+	input, err := charsetReader(label, bytes.NewReader(data))
+	if err != nil {
+		input = bytes.NewReader(data)
+	}
+	decoder := xml.NewDecoder(input)
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+	// The document is UTF-8 from here on, whatever it declares.
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	return decoder
+}
+
+// contentTypeCharset returns the charset parameter of a Content-Type header,
+// or an empty string when it has none.
+func contentTypeCharset(contentType string) string {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(params["charset"])
+}
+
+// declaredCharset returns the encoding named by the XML declaration of a
+// document, or an empty string when it names none.
+func declaredCharset(data []byte) string {
+	m := xmlEncodingPattern.FindSubmatch(data)
+	if m == nil {
+		return ""
+	}
+	return strings.TrimSpace(string(m[1]))
+}
+
+// knownCharset reports whether label names an encoding charsetReader
+// converts from.
+func knownCharset(label string) bool {
+	e, _ := htmlcharset.Lookup(label)
+	return e != nil
+}
+
+func isUTF8Charset(label string) bool {
+	switch strings.ToLower(strings.TrimSpace(label)) {
+	case "utf-8", "utf8":
+		return true
+	default:
+		return false
+	}
+}
+
+// cleanXML removes the byte order mark and whitespace before the document
+// and the control characters XML does not allow anywhere in it. It works on
+// bytes since the document may not be UTF-8 yet.
+func cleanXML(data []byte) []byte {
+	data = bytes.TrimLeft(bytes.TrimPrefix(data, utf8BOM), " \t\r\n")
+	cleaned := make([]byte, 0, len(data))
+	for _, c := range data {
+		if c < 0x20 && c != '\t' && c != '\n' && c != '\r' {
+			continue
+		}
+		cleaned = append(cleaned, c)
+	}
+	return cleaned
+}
+
+// charsetReader converts a document to UTF-8 from an encoding browsers know,
+// using the same names. As browsers do, ISO-8859-1 and ASCII are read as
+// windows-1252, which they are usually mislabelled.
+func charsetReader(label string, input io.Reader) (io.Reader, error) {
+	if isUTF8Charset(label) {
+		return input, nil
+	}
+	return htmlcharset.NewReaderLabel(label, input)
+}
@@ -0,0 +1,200 @@
+package syndication
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestParseBrokenFeeds decodes feeds with the mistakes found in the wild,
+// kept in testdata/broken.
+func TestParseBrokenFeeds(t *testing.T) {
+	tests := []struct {
+		file       string
+		charset    string
+		title      string
+		siteURL    string
+		entryTitle string
+		entryLink  string
+	}{
+		{
+			file:       "html_entities.xml",
+			title:      "Café Society — Blog",
+			siteURL:    "https://cafe.example/",
+			entryTitle: "Crème brûlée & more",
+			entryLink:  "https://cafe.example/creme",
+		},
+		{
+			file:       "latin1.xml",
+			title:      "Café Müller",
+			siteURL:    "https://mueller.example/",
+			entryTitle: "Äpfel und Birnen",
+			entryLink:  "https://mueller.example/aepfel",
+		},
+		{
+			file:       "cp1252.xml",
+			title:      "“Smart” Quotes – Daily",
+			siteURL:    "https://quotes.example/",
+			entryTitle: "It’s €5… really",
+			entryLink:  "https://quotes.example/euro",
+		},
+		{
+			file:       "latin1_undeclared.xml",
+			title:      "Niño’s Blog",
+			siteURL:    "https://nino.example/",
+			entryTitle: "Año nuevo",
+			entryLink:  "https://nino.example/ano",
+		},
+		{
+			file:       "latin1_undeclared.xml",
+			charset:    "ISO-8859-1",
+			title:      "Niño’s Blog",
+			siteURL:    "https://nino.example/",
+			entryTitle: "Año nuevo",
+			entryLink:  "https://nino.example/ano",
+		},
+		{
+			file:       "stray_ampersand.xml",
+			title:      "Tom & Jerry News",
+			siteURL:    "https://toons.example/?lang=en&section=news",
+			entryTitle: "Q&A: chasing & hiding",
+			entryLink:  "https://toons.example/post?id=7&ref=rss",
+		},
+		{
+			file:       "bom.xml",
+			title:      "Byte Order Blog",
+			siteURL:    "https://bom.example/",
+			entryTitle: "Über BOMs",
+			entryLink:  "https://bom.example/uber",
+		},
+		{
+			file:       "missing_channel_link.xml",
+			title:      "feeds.example",
+			entryTitle: "Relative item",
+			entryLink:  "https://feeds.example/posts/1",
+		},
+		{
+			// The Content-Type is right and the declaration is not.
+			file:       "mislabelled_declaration.xml",
+			charset:    "utf-8",
+			title:      "Zürich → Genève",
+			siteURL:    "https://swiss.example/",
+			entryTitle: "Grüezi",
+			entryLink:  "https://swiss.example/gruezi",
+		},
+		{
+			// A UTF-8 charset is not trusted for a document that is not.
+			file:       "latin1.xml",
+			charset:    "UTF-8",
+			title:      "Café Müller",
+			siteURL:    "https://mueller.example/",
+			entryTitle: "Äpfel und Birnen",
+			entryLink:  "https://mueller.example/aepfel",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file+"/"+tt.charset, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", "broken", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			feed, err := parseFeed(data, "https://feeds.example/feed.xml", tt.charset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if feed.Title != tt.title {
+				t.Errorf("title = %q, want %q", feed.Title, tt.title)
+			}
+			if feed.SiteURL != tt.siteURL {
+				t.Errorf("site URL = %q, want %q", feed.SiteURL, tt.siteURL)
+			}
+			if len(feed.Entries) != 1 {
+				t.Fatalf("got %d entries, want 1", len(feed.Entries))
+			}
+			entry := feed.Entries[0]
+			if entry.Title != tt.entryTitle {
+				t.Errorf("entry title = %q, want %q", entry.Title, tt.entryTitle)
+			}
+			if entry.Link != tt.entryLink {
+				t.Errorf("entry link = %q, want %q", entry.Link, tt.entryLink)
+			}
+		})
+	}
+}
+
+func TestNewXMLDecoderCharset(t *testing.T) {
+	// "é" in ISO-8859-1, in a document that declares no encoding.
+	doc := []byte("<title>caf\xe9</title>")
+
+	tests := []struct {
+		charset string
+		want    string
+	}{
+		{"", "café"},
+		{"iso-8859-1", "café"},
+		{"Windows-1252", "café"},
+		{"utf-8", "café"},
+	}
+	for _, tt := range tests {
+		var title string
+		err := newXMLDecoder(doc, tt.charset).Decode(&title)
+		if err != nil {
+			t.Errorf("charset %q: %v", tt.charset, err)
+			continue
+		}
+		if title != tt.want {
+			t.Errorf("charset %q: got %q, want %q", tt.charset, title, tt.want)
+		}
+	}
+
+	// An unknown charset falls back to the declaration.
+	var title string
+	err := newXMLDecoder([]byte(`<?xml version="1.0" encoding="latin1"?><title>caf`+"\xe9"+`</title>`), "x-bogus").Decode(&title)
+	if err != nil || title != "café" {
+		t.Errorf("unknown charset: got %q, %v", title, err)
+	}
+
+	// Legacy encodings are converted. Documents in unknown encodings, or
+	// wrongly declared as UTF-8, are read as UTF-8 when they are valid UTF-8
+	// and as windows-1252 otherwise rather than refused.
+	declared := []struct {
+		encoding string
+		text     string
+		want     string
+	}{
+		{"ISO-8859-2", "\xa3\xf3d\xbc", "Łódź"},
+		{"KOI8-R", "\xf0\xd2\xc9\xd7\xc5\xd4", "Привет"},
+		{"Shift_JIS", "\x93\xfa\x96\x7b\x8c\xea", "日本語"},
+		{"GB2312", "\xd6\xd0\xce\xc4", "中文"},
+		{"x-unknown", "caf\xe9", "café"},
+		{"x-unknown", "café", "café"},
+		{"UTF-8", "caf\xe9", "café"},
+	}
+	for _, tt := range declared {
+		doc := `<?xml version="1.0" encoding="` + tt.encoding + `"?><title>` + tt.text + `</title>`
+		for _, charset := range []string{"", tt.encoding} {
+			var title string
+			err := newXMLDecoder([]byte(doc), charset).Decode(&title)
+			if err != nil || title != tt.want {
+				t.Errorf("%s, charset %q: got %q, %v; want %q", tt.encoding, charset, title, err, tt.want)
+			}
+		}
+	}
+}
+
+func TestContentTypeCharset(t *testing.T) {
+	tests := map[string]string{
+		"":                                     "",
+		"application/rss+xml":                  "",
+		"application/rss+xml; charset=utf-8":   "utf-8",
+		`text/xml; Charset="ISO-8859-1"`:       "ISO-8859-1",
+		"text/xml;charset=windows-1252;foo=1":  "windows-1252",
+		"not a media type; charset=iso-8859-1": "",
+	}
+	for contentType, want := range tests {
+		if got := contentTypeCharset(contentType); got != want {
+			t.Errorf("contentTypeCharset(%q) = %q, want %q", contentType, got, want)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE entries ADD COLUMN guid TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+UPDATE entries
+SET guid = external_url;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+UPDATE entries
+SET guid = guid || '#' || id
+WHERE id NOT IN (
+    SELECT MIN(id)
+    FROM entries
+    GROUP BY feed_id, guid
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE UNIQUE INDEX entries_feed_id_guid_idx ON entries(feed_id, guid);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX entries_feed_id_guid_idx;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE entries DROP COLUMN guid;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE feeds ADD COLUMN etag TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE feeds ADD COLUMN last_modified TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE feeds DROP COLUMN last_modified;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE feeds DROP COLUMN etag;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE feeds ADD COLUMN error_count INTEGER NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE feeds ADD COLUMN last_error TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE feeds ADD COLUMN last_error_at TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE feeds DROP COLUMN last_error_at;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE feeds DROP COLUMN last_error;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE feeds DROP COLUMN error_count;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE enclosures (
+    id                INTEGER PRIMARY KEY,
+    entry_id          INTEGER NOT NULL,
+    url               TEXT NOT NULL,
+    mime_type         TEXT NOT NULL,
+    title             TEXT NOT NULL,
+    length            INTEGER DEFAULT 0 NOT NULL,
+    duration          INTEGER DEFAULT 0 NOT NULL,
+    episode           TEXT NOT NULL,
+    image_url         TEXT NOT NULL,
+    playback_position INTEGER DEFAULT 0 NOT NULL,
+    FOREIGN KEY (entry_id) REFERENCES entries(id) ON DELETE CASCADE,
+    UNIQUE (entry_id, url)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE enclosures;
+-- +goose StatementEnd
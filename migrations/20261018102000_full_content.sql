@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE feeds ADD COLUMN fetch_full_content INTEGER NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE entries ADD COLUMN full_content TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE entries DROP COLUMN full_content;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE feeds DROP COLUMN fetch_full_content;
+-- +goose StatementEnd
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE tags (
+    id   INTEGER PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE COLLATE NOCASE
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TABLE entry_tags (
+    entry_id INTEGER NOT NULL,
+    tag_id   INTEGER NOT NULL,
+    PRIMARY KEY (entry_id, tag_id),
+    FOREIGN KEY (entry_id) REFERENCES entries(id) ON DELETE CASCADE,
+    FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX entry_tags_tag_id_idx ON entry_tags (tag_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE entry_tags;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE tags;
+-- +goose StatementEnd
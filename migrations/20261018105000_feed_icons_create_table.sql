@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE feed_icons (
+    feed_id    INTEGER PRIMARY KEY,
+    url        TEXT NOT NULL,
+    content    BLOB NOT NULL,
+    hash       TEXT NOT NULL,
+    fetched_at TEXT NOT NULL,
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE feed_icons;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE websub_subscriptions (
+    feed_id          INTEGER PRIMARY KEY,
+    hub_url          TEXT NOT NULL,
+    topic_url        TEXT NOT NULL,
+    secret           TEXT NOT NULL,
+    state            TEXT NOT NULL DEFAULT 'pending',
+    lease_expires_at TEXT NOT NULL DEFAULT '',
+    requested_at     TEXT NOT NULL,
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE websub_subscriptions;
+-- +goose StatementEnd
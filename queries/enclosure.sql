@@ -0,0 +1,56 @@
+-- name: CreateEnclosure :exec
+INSERT INTO enclosures (
+    entry_id,
+    url,
+    mime_type,
+    title,
+    length,
+    duration,
+    episode,
+    image_url
+)
+VALUES (
+    ?, ?, ?, ?, ?, ?, ?, ?
+)
+ON CONFLICT (entry_id, url) DO UPDATE
+SET mime_type = excluded.mime_type,
+    title = excluded.title,
+    length = excluded.length,
+    duration = excluded.duration,
+    episode = excluded.episode,
+    image_url = excluded.image_url;
+
+-- name: GetEnclosure :one
+SELECT *
+FROM enclosures
+WHERE id = ?;
+
+-- name: GetEntryEnclosures :many
+SELECT *
+FROM enclosures
+WHERE entry_id = ?
+ORDER BY id;
+
+-- name: UpdateEnclosurePosition :exec
+UPDATE enclosures
+SET playback_position = ?
+WHERE id = ?;
+
+-- name: DeleteEntryEnclosures :exec
+DELETE
+FROM enclosures
+WHERE entry_id = ?;
+
+-- name: DeleteStaleEntryEnclosures :exec
+DELETE
+FROM enclosures
+WHERE entry_id = ? AND url NOT IN (sqlc.slice('urls'));
+
+-- name: DeleteFeedEnclosures :exec
+DELETE
+FROM enclosures
+WHERE entry_id IN (
+    SELECT id
+    FROM entries
+    WHERE feed_id = ?
+);
@@ -1,16 +1,27 @@
 -- name: CreateEntry :exec
 INSERT INTO entries (
     feed_id,
+    guid,
     title,
     author,
     content,
     external_url,
     published_at,
-    created_at
+    created_at,
+    thumbnail_url,
+    summary
 )
 VALUES (
-    ?, ?, ?, ?, ?, ?, ?
-);
+    ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
+)
+ON CONFLICT (feed_id, guid) DO UPDATE
+SET title = excluded.title,
+    author = excluded.author,
+    content = excluded.content,
+    external_url = excluded.external_url,
+    published_at = excluded.published_at,
+    thumbnail_url = excluded.thumbnail_url,
+    summary = excluded.summary;
 
 -- name: GetUnreadEntries :many
 SELECT feeds.title AS feed_title, entries.*
@@ -35,6 +46,21 @@ JOIN feeds
     ON entries.feed_id = feeds.id
 WHERE entries.id = ?;
 
+-- name: CountRecentFeedEntries :one
+SELECT COUNT(*)
+FROM entries
+WHERE feed_id = ? AND published_at >= ?;
+
+-- name: GetEntryIDByGuid :one
+SELECT id
+FROM entries
+WHERE feed_id = ? AND guid = ?;
+
+-- name: GetEntryPublishedAt :one
+SELECT published_at
+FROM entries
+WHERE feed_id = ? AND guid = ?;
+
 -- name: MarkEntriesRead :exec
 UPDATE entries
 SET read = 1
@@ -49,3 +75,27 @@ WHERE id = ?;
 -- name: DeleteEntry :exec
 DELETE FROM entries
 WHERE id = ?;
+
+-- name: DeleteFeedEntries :exec
+DELETE FROM entries
+WHERE feed_id = ?;
+
+-- name: GetEntriesWithoutFullContent :many
+SELECT id, external_url
+FROM entries
+WHERE feed_id = ? AND created_at >= ? AND full_content = '' AND external_url != '';
+
+-- name: UpdateEntryFullContent :exec
+UPDATE entries
+SET full_content = ?
+WHERE id = ?;
+
+-- name: GetLegacyEntryLinks :many
+SELECT external_url
+FROM entries
+WHERE feed_id = ? AND guid = external_url;
+
+-- name: UpdateLegacyEntryGuid :exec
+UPDATE OR IGNORE entries
+SET guid = ?
+WHERE feed_id = ? AND external_url = ? AND guid = external_url;
@@ -11,11 +11,23 @@ INSERT INTO feeds (
     site_url,
     type,
     updated_at,
-    checked_at
+    checked_at,
+    etag,
+    last_modified,
+    next_check_at,
+    scraper_rules,
+    request_options,
+    update_hints
 )
-VALUES (?, ?, ?, ?, ?, ?, ?)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 RETURNING *;
 
+-- name: GetDueFeeds :many
+SELECT *
+FROM feeds
+WHERE next_check_at <= ? AND disabled = 0
+ORDER BY next_check_at;
+
 -- name: GetFeed :one
 SELECT *
 FROM feeds
@@ -34,5 +46,70 @@ WHERE feed_id = ?;
 
 -- name: UpdateFeedCheckedAt :exec
 UPDATE feeds
-SET checked_at = ?
+SET checked_at = ?,
+    etag = ?,
+    last_modified = ?,
+    next_check_at = ?,
+    update_hints = ?,
+    error_count = 0,
+    last_error = '',
+    last_error_at = ''
+WHERE id = ?;
+
+-- name: RecordFeedError :exec
+UPDATE feeds
+SET error_count = error_count + 1,
+    last_error = ?,
+    last_error_at = ?,
+    next_check_at = ?,
+    disabled = ?
+WHERE id = ?;
+
+-- name: EnableFeed :exec
+UPDATE feeds
+SET disabled = 0,
+    error_count = 0,
+    last_error = '',
+    last_error_at = '',
+    next_check_at = ''
+WHERE id = ?;
+
+-- name: FeedURLExists :one
+SELECT EXISTS (
+    SELECT 1
+    FROM feeds
+    WHERE feeds.feed_url = ?
+    UNION ALL
+    SELECT 1
+    FROM feed_aliases
+    WHERE feed_aliases.url = ?
+) AS url_exists;
+
+-- name: UpdateFeedURL :exec
+UPDATE feeds
+SET feed_url = ?
+WHERE id = ?;
+
+-- name: CreateFeedAlias :exec
+INSERT INTO feed_aliases (
+    feed_id,
+    url,
+    created_at
+)
+VALUES (?, ?, ?)
+ON CONFLICT (url) DO NOTHING;
+
+-- name: DeleteFeedAliases :exec
+DELETE
+FROM feed_aliases
+WHERE feed_id = ?;
+
+-- name: SetFeedFetchFullContent :exec
+UPDATE feeds
+SET fetch_full_content = ?
+WHERE id = ?;
+
+-- name: SetFeedRequestOptions :exec
+UPDATE feeds
+SET request_options = ?
 WHERE id = ?;
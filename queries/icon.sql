@@ -0,0 +1,24 @@
+-- name: UpsertFeedIcon :exec
+INSERT INTO feed_icons (
+    feed_id,
+    url,
+    content,
+    hash,
+    fetched_at
+)
+VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (feed_id) DO UPDATE
+SET url = excluded.url,
+    content = excluded.content,
+    hash = excluded.hash,
+    fetched_at = excluded.fetched_at;
+
+-- name: GetFeedIcon :one
+SELECT *
+FROM feed_icons
+WHERE feed_id = ?;
+
+-- name: DeleteFeedIcon :exec
+DELETE
+FROM feed_icons
+WHERE feed_id = ?;
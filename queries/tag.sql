@@ -0,0 +1,66 @@
+-- name: UpsertTag :one
+INSERT INTO tags (name)
+VALUES (?)
+ON CONFLICT (name) DO UPDATE
+SET name = tags.name
+RETURNING id;
+
+-- name: CreateEntryTag :exec
+INSERT INTO entry_tags (
+    entry_id,
+    tag_id
+)
+VALUES (?, ?)
+ON CONFLICT (entry_id, tag_id) DO NOTHING;
+
+-- name: DeleteEntryTags :exec
+DELETE
+FROM entry_tags
+WHERE entry_id = ?;
+
+-- name: DeleteFeedEntryTags :exec
+DELETE
+FROM entry_tags
+WHERE entry_id IN (
+    SELECT id
+    FROM entries
+    WHERE feed_id = ?
+);
+
+-- name: GetEntryTags :many
+SELECT tags.*
+FROM tags
+JOIN entry_tags
+    ON entry_tags.tag_id = tags.id
+WHERE entry_tags.entry_id = ?
+ORDER BY tags.name;
+
+-- name: GetTags :many
+SELECT tags.id,
+    tags.name,
+    COUNT(*) AS entry_count,
+    CAST(SUM(entries.read = 0) AS INTEGER) AS unread_count
+FROM tags
+JOIN entry_tags
+    ON entry_tags.tag_id = tags.id
+JOIN entries
+    ON entry_tags.entry_id = entries.id
+JOIN feeds
+    ON entries.feed_id = feeds.id
+GROUP BY tags.id
+ORDER BY tags.name;
+
+-- name: GetTag :one
+SELECT *
+FROM tags
+WHERE id = ?;
+
+-- name: GetTagEntries :many
+SELECT feeds.title AS feed_title, entries.*
+FROM entries
+JOIN feeds
+    ON entries.feed_id = feeds.id
+JOIN entry_tags
+    ON entry_tags.entry_id = entries.id
+WHERE entry_tags.tag_id = ?
+ORDER BY published_at DESC;
@@ -0,0 +1,42 @@
+-- name: UpsertWebSubSubscription :exec
+INSERT INTO websub_subscriptions (
+    feed_id,
+    hub_url,
+    topic_url,
+    secret,
+    state,
+    requested_at
+)
+VALUES (?, ?, ?, ?, ?, ?)
+ON CONFLICT (feed_id) DO UPDATE
+SET hub_url = excluded.hub_url,
+    topic_url = excluded.topic_url,
+    secret = excluded.secret,
+    state = excluded.state,
+    requested_at = excluded.requested_at;
+
+-- name: GetWebSubSubscription :one
+SELECT *
+FROM websub_subscriptions
+WHERE feed_id = ?;
+
+-- name: GetExpiringWebSubSubscriptions :many
+SELECT *
+FROM websub_subscriptions
+WHERE state = 'active' AND lease_expires_at <= ? AND requested_at <= ?;
+
+-- name: ActivateWebSubSubscription :exec
+UPDATE websub_subscriptions
+SET state = 'active',
+    lease_expires_at = ?
+WHERE feed_id = ?;
+
+-- name: SetWebSubSubscriptionState :exec
+UPDATE websub_subscriptions
+SET state = ?
+WHERE feed_id = ?;
+
+-- name: DeleteWebSubSubscription :exec
+DELETE
+FROM websub_subscriptions
+WHERE feed_id = ?;